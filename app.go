@@ -1,19 +1,26 @@
 package braza
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
-	"log"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
+	"github.com/quic-go/quic-go/http3"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 var (
@@ -35,8 +42,29 @@ var (
 	listenAll    bool
 	localAddress = getOutboundIP()
 	mapStackApps = map[string]*App{}
+	mapStackMu   sync.Mutex
 )
 
+// register the app in mapStackApps (the apps of process, for Daemon and the graceful reload)
+func stackApp(app *App) {
+	mapStackMu.Lock()
+	defer mapStackMu.Unlock()
+	if _, ok := mapStackApps[app.uuid]; !ok {
+		mapStackApps[app.uuid] = app
+	}
+}
+
+// returns a copy of mapStackApps: the apps are registered by other goroutines (Daemon)
+func stackApps() []*App {
+	mapStackMu.Lock()
+	defer mapStackMu.Unlock()
+	apps := make([]*App, 0, len(mapStackApps))
+	for _, app := range mapStackApps {
+		apps = append(apps, app)
+	}
+	return apps
+}
+
 var (
 	env          string
 	port         string
@@ -134,12 +162,34 @@ type App struct {
 	*/
 	TearDownRequest Func
 
+	/*
+		resolve the tenant of each request (after the match of route). see braza.Tenant
+			app.TenantResolver = braza.TenantFromHeader("X-Tenant-ID", func(id string) (*braza.Tenant, error) {
+				return tenants[id], nil
+			})
+	*/
+	TenantResolver TenantResolver
+
 	routers      []*Router
 	routerByName map[string]*Router
+	converters   map[string]*Converter // custom converters of route variables
+	codecs       map[string]*Codec     // custom codecs of content types
+	codecTypes   []string              // the content types of custom codecs, by order of register
+	validators   map[string]Validator  // custom validation rules of schemas
+	hubs         []*Hub                // the hubs of websocket routes, closed on shutdown
 
 	// The Http.Server
 	Srv *http.Server
 
+	h3Srv *http3.Server // only if EnableHTTP3 and ListenTLS
+
+	liveReload *liveReloader // only in dev mode with LiveReload
+
+	listener  net.Listener
+	inherited bool // listener received from systemd socket activation or a graceful reload
+	draining  atomic.Bool
+	drained   chan struct{}
+
 	uuid  string
 	built bool
 }
@@ -201,31 +251,53 @@ func (app *App) setEnv() {
 	}
 }
 
-func (app *App) logStarterListener() {
-	addr, port, err := net.SplitHostPort(app.Srv.Addr)
-	if err != nil {
-		l.err.Panic(err)
+func (app *App) logStarterListener(ln net.Listener, tls bool) {
+	schema := "http"
+	if tls {
+		schema = "https"
+	}
+	origin := ""
+	if app.inherited {
+		origin = " (inherited socket)"
 	}
 	envDev := app.Env == "development"
-	if listenAll {
-		app.Srv.Addr = localAddress
+	if ln.Addr().Network() == "unix" {
 		if envDev {
-			l.Defaultf("Server is listening on all address in %sdevelopment mode%s", _RED, _RESET)
+			l.Defaultf("Server is listening in %sdevelopment mode%s", _RED, _RESET)
 		} else {
-			l.Default("Server is listening on all address")
+			l.Default("Server is linsten")
 		}
-		l.info.Printf("          listening on: http://%s:%s", getOutboundIP(), port)
-		l.info.Printf("          listening on: http://0.0.0.0:%s", port)
+		l.info.Printf("          listening on: %s+unix://%s%s", schema, ln.Addr().String(), origin)
 	} else {
-		if envDev {
-			l.Defaultf("Server is listening in %sdevelopment mode%s", _RED, _RESET)
+		addr, port, err := net.SplitHostPort(ln.Addr().String())
+		if err != nil {
+			l.err.Panic(err)
+		}
+		if listenAll {
+			if envDev {
+				l.Defaultf("Server is listening on all address in %sdevelopment mode%s", _RED, _RESET)
+			} else {
+				l.Default("Server is listening on all address")
+			}
+			l.info.Printf("          listening on: %s://%s:%s%s", schema, getOutboundIP(), port, origin)
+			l.info.Printf("          listening on: %s://0.0.0.0:%s%s", schema, port, origin)
 		} else {
-			l.Default("Server is linsten")
+			if envDev {
+				l.Defaultf("Server is listening in %sdevelopment mode%s", _RED, _RESET)
+			} else {
+				l.Default("Server is linsten")
+			}
+			if addr == "" || addr == "::" || addr == "0.0.0.0" {
+				addr = "localhost"
+			}
+			l.info.Printf("          listening on: %s://%s%s", schema, net.JoinHostPort(addr, port), origin)
 		}
-		if addr == "" {
-			addr = "localhost"
+		if app.h3Srv != nil {
+			l.info.Printf("          serving HTTP/3 (QUIC) on: udp/%s", port)
 		}
-		l.info.Printf("          listening on: http://%s:%s", addr, port)
+	}
+	if app.EnableH2C && !tls {
+		l.info.Print("          serving HTTP/2 cleartext (h2c)")
 	}
 	if envDev {
 		if app.Servername != "" {
@@ -237,15 +309,55 @@ func (app *App) logStarterListener() {
 /*
 SERVER funcs
 */
-func (app *App) startListener(c chan error) { c <- app.Srv.ListenAndServe() }
+func (app *App) startListener(ln net.Listener, c chan error) { c <- app.Srv.Serve(ln) }
+
+func (app *App) startListenerTLS(ln net.Listener, privKey, pubKey string, c chan error) {
+	c <- app.Srv.ServeTLS(ln, privKey, pubKey)
+}
+
+// HTTP/3 is optional: if it fails, the app keeps serving in http/1 and http/2
+func (app *App) startListenerHTTP3(privKey, pubKey string) {
+	deadline := time.Now().Add(app.GracefulTimeout)
+	for {
+		err := app.h3Srv.ListenAndServeTLS(privKey, pubKey)
+		if err == nil || errors.Is(err, http.ErrServerClosed) {
+			return
+		}
+		// in a graceful reload, the old process keeps the udp port until it is drained
+		if app.inherited && errors.Is(err, syscall.EADDRINUSE) && time.Now().Before(deadline) {
+			time.Sleep(time.Second / 2)
+			continue
+		}
+		l.err.Println("http3:", err)
+		return
+	}
+}
 
-func (app *App) startListenerTLS(privKey, pubKey string, c chan error) {
-	c <- app.Srv.ListenAndServeTLS(privKey, pubKey)
+// close all servers of app (http/1, http/2 and http/3)
+func (app *App) closeSrv() {
+	if app.Srv != nil {
+		app.Srv.Close()
+	}
+	for _, hub := range app.hubs {
+		hub.Close()
+	}
+	if app.h3Srv != nil {
+		app.h3Srv.Close()
+	}
+	app.listener = nil // closed by Srv.Close
 }
 
 func (app *App) parseSrvApp(addr string) {
 	app.Srv.Handler = app
+	if app.EnableH2C {
+		// h2c only upgrades cleartext connections, TLS keeps using the default HTTP/2 of net/http
+		app.Srv.Handler = h2c.NewHandler(app, &http2.Server{})
+	}
 	app.Srv.MaxHeaderBytes = 1 << 20
+	// the websocket connections are hijacked: Srv.Shutdown doesn't close them
+	for _, hub := range app.hubs {
+		app.Srv.RegisterOnShutdown(hub.Close)
+	}
 	if app.Srv.Addr == "" && addr != "" {
 		app.Srv.Addr = addr
 	} else if app.Srv.Addr == "" {
@@ -256,41 +368,60 @@ func (app *App) parseSrvApp(addr string) {
 
 func runSrv(app *App, privKey, pubKey string, host ...string) (err error) {
 	app.Build(host...)
-	var reboot = make(chan bool)
+	if needSupervisor(app) {
+		return runSupervisor(app)
+	}
 	var srvErr = make(chan error)
 
-	if app.Env == "development" && !app.DisableFileWatcher {
-		go fileWatcher(reboot)
+	stackApp(app)
+	app.drained = make(chan struct{})
+	if app.liveReload != nil {
+		go app.liveReload.watch(app)
+	}
+	if app.Env != "development" && !app.DisableGracefulReload {
+		watchReloadSignals()
+	}
+
+	ln, err := app.listen()
+	if err != nil {
+		l.err.Println(err)
+		return err
+	}
+	if tcpAddr, ok := ln.Addr().(*net.TCPAddr); ok {
+		app.Srv.Addr = tcpAddr.String() // if port is ':0' or the socket is inherited
+	}
+
+	isTLS := privKey != "" && pubKey != ""
+	if _, isTCP := ln.Addr().(*net.TCPAddr); isTLS && isTCP && app.EnableHTTP3 {
+		app.h3Srv = &http3.Server{
+			Addr:           app.Srv.Addr,
+			Handler:        app,
+			MaxHeaderBytes: app.Srv.MaxHeaderBytes,
+		}
 	}
 
 	if !app.Silent {
-		app.logStarterListener()
+		app.logStarterListener(ln, isTLS)
 	}
 
-	if privKey != "" && pubKey != "" {
-		go app.startListenerTLS(privKey, pubKey, srvErr)
-	} else {
-		go app.startListener(srvErr)
-	}
-
-	if !app.DisableFileWatcher {
-		for {
-			select {
-			case <-reboot:
-				app.Srv.Close()
-				selfReboot()
-			case err = <-srvErr:
-				if !errors.Is(err, http.ErrServerClosed) {
-					l.err.Println(err)
-					return err
-				}
-			}
+	if isTLS {
+		go app.startListenerTLS(ln, privKey, pubKey, srvErr)
+		if app.h3Srv != nil {
+			go app.startListenerHTTP3(privKey, pubKey)
 		}
 	} else {
-		e := <-srvErr
-		l.err.Println(e)
-		return e
+		go app.startListener(ln, srvErr)
 	}
+	notifyReady()
+
+	err = <-srvErr
+	if app.draining.Load() {
+		<-app.drained // graceful reload: wait for the active connections
+		return err
+	}
+	l.err.Println(err)
+	app.closeSrv()
+	return err
 }
 
 // Start Listener in http
@@ -301,6 +432,31 @@ func (app *App) ListenTLS(certFile, certKey string, host ...string) (err error)
 	return runSrv(app, certFile, certKey, host...)
 }
 
+/*
+Serve http on a pre-opened listener
+
+	ln, _ := net.Listen("tcp", ":8080")
+	app.Serve(ln)
+*/
+func (app *App) Serve(ln net.Listener) (err error) {
+	app.listener = ln
+	return runSrv(app, "", "")
+}
+
+/*
+Start Listener in a unix domain socket. If mode is 0, the default permissions are kept
+
+	app.ListenUnix("/run/app/app.sock", 0660)
+*/
+func (app *App) ListenUnix(path string, mode os.FileMode) (err error) {
+	ln, err := listenUnix(path, mode)
+	if err != nil {
+		l.err.Println(err)
+		return err
+	}
+	return app.Serve(ln)
+}
+
 /*
 APP methods
 */
@@ -310,31 +466,27 @@ func (app *App) parseApp() {
 	app.uuid = uuid.NewString()
 	app.setEnv()
 	app.checkConfig()
+	app.servernames = nil
 	if app.Servername != "" {
-
-		srv := strings.TrimPrefix(
-			strings.TrimPrefix(
-				app.Servername, "https//",
-			),
-			"https://",
-		) // so pra evitar erros
-
-		srv = strings.TrimPrefix(
-			strings.TrimSuffix(
-				srv, "/",
-			), ".") // so pra evitar erros²
-
-		h, p, err := net.SplitHostPort(srv)
-		if err != nil && p != "" && h != "" {
-			log.Fatal(err)
-		}
+		h, p := normalizeServername(app.Servername)
 		if p != "" {
 			app.serverport = p
 		}
 		if h != "" {
 			app.Servername = h
+			app.servernames = append(app.servernames, h)
 		}
 	}
+	for _, srv := range app.Servernames {
+		h, _ := normalizeServername(srv)
+		if h == "" || slices.Contains(app.servernames, h) {
+			continue
+		}
+		if app.Servername == "" {
+			app.Servername = h
+		}
+		app.servernames = append(app.servernames, h)
+	}
 
 	if env, ok := allowEnv[app.Env]; ok {
 		app.Env = env
@@ -357,6 +509,15 @@ func (app *App) parseApp() {
 		})
 	}
 
+	if app.LiveReload && app.Env == "development" {
+		app.liveReload = newLiveReloader()
+		app.AddRoute(&Route{
+			Url:  liveReloadUrl,
+			Func: liveReloadHandler,
+			Name: liveReloadName,
+		})
+	}
+
 	// se o usuario mudar o router principal, isso evita alguns erro
 	if !app.main {
 		app.main = true
@@ -379,17 +540,35 @@ func (app *App) parseApp() {
 	if !slices.Contains(app.routers, app.Router) {
 		app.routers = append(app.routers, app.Router)
 	}
+
+	// sub routers are matched like the others, before their parents
+	routers := []*Router{}
+	for _, router := range app.routers {
+		routers = append(routers, router.flatten()...)
+	}
+	app.routers = routers
+
 	for _, router := range app.routers {
-		router.parse(app.Servername)
+		if router.Name != "" && router != app.Router {
+			if r, ok := app.routerByName[router.Name]; ok && r != router {
+				panic(fmt.Errorf("router '%s' already regitered", router.Name))
+			}
+			app.routerByName[router.Name] = router
+		}
+		router.parse(app)
 		for n, r := range router.routesByName {
 			app.routesByName[n] = r
 		}
 	}
+	// the routers of specific hosts are matched first ('api.example.com' before '*.example.com')
+	slices.SortStableFunc(app.routers, func(a, b *Router) int {
+		return a.hostRank() - b.hostRank()
+	})
 	app.built = true
 }
 
 /*
-Custom Http Error Handler
+Custom Http Error Handler of app. Used if the router of request doesn't have a handler for the status code
 
 	app.ErrorHandler(401,(ctx *braza.Ctx) {
 		ctx.HTML("Access denied",401)
@@ -398,12 +577,7 @@ Custom Http Error Handler
 		ctx.HTML("Hey boy, you're a little lost",404)
 	})
 */
-func (app *App) ErrorHandler(statusCode int, f Func) {
-	if app.errHandlers == nil {
-		app.errHandlers = map[int]Func{}
-	}
-	app.errHandlers[statusCode] = f
-}
+func (app *App) ErrorHandler(statusCode int, f Func) { app.Router.ErrorHandler(statusCode, f) }
 
 /*
 Register Router in app
@@ -426,7 +600,8 @@ func (app *App) Mount(routers ...*Router) {
 		} else if _, ok := app.routerByName[router.Name]; ok {
 			panic(fmt.Errorf("router '%s' already regitered", router.Name))
 		}
-		if !slices.Contains(app.routers, app.Router) {
+		if !slices.Contains(app.routers, router) {
+			app.routerByName[router.Name] = router
 			app.routers = append(app.routers, router)
 		}
 	}
@@ -484,16 +659,6 @@ HTTP funcs
 
 func (app *App) match(ctx *Ctx) {
 	rq := ctx.Request
-	if app.Servername != "" {
-		if net.ParseIP(rq.Host) != nil {
-			ctx.NotFound()
-		}
-
-		if !strings.Contains(rq.Host, app.Servername) {
-			ctx.NotFound()
-		}
-	}
-
 	for _, router := range app.routers {
 		if router.match(ctx) {
 			if router.StrictSlash && !strings.HasSuffix(rq.URL.Path, "/") {
@@ -507,14 +672,36 @@ func (app *App) match(ctx *Ctx) {
 		}
 	}
 	mi := ctx.MatchInfo
+	if mi.MethodNotAllowed == nil || mi.Router == nil {
+		mi.Router = app.routerByPrefix(ctx)
+	}
 	if mi.MethodNotAllowed != nil {
 		ctx.MethodNotAllowed()
 	}
 	ctx.NotFound()
 }
 
+// returns the router that owns the url of request (by subdomain and the longest prefix), for 404 and 405 errors
+func (app *App) routerByPrefix(ctx *Ctx) *Router {
+	var router *Router
+	for _, r := range app.routers {
+		if !r.matchHost(ctx) || !r.matchPrefix(ctx.Request.URL.Path) {
+			continue
+		}
+		if router == nil || len(r.prefix) > len(router.prefix) {
+			router = r
+		}
+	}
+	return router
+}
+
 // exec route and handle errors of application
 func (app *App) execRoute(ctx *Ctx) {
+	if app.Env == "development" {
+		if buildErr := devBuildError(); buildErr != "" {
+			devBuildErrorHandler(ctx, buildErr)
+		}
+	}
 	app.match(ctx)
 
 	rq := ctx.Request
@@ -531,14 +718,67 @@ func (app *App) execRoute(ctx *Ctx) {
 		optionsHandler(ctx)
 		return
 	}
+	app.execChain(ctx)
+}
+
+// exec the middlewares and the route func, with the timeout of route.
+// the timeout only cancels the context: the 503 is written after ctx.Next() returns (see Route.Timeout)
+func (app *App) execChain(ctx *Ctx) {
+	timeout := ctx.MatchInfo.Route.Timeout
+	if timeout <= 0 {
+		ctx.Next()
+		return
+	}
+	c, cancel := context.WithTimeout(ctx.Request.Context(), timeout)
+	defer cancel()
+	ctx.Request.raw = ctx.Request.raw.WithContext(c)
+
+	defer func() {
+		if c.Err() != context.DeadlineExceeded {
+			return
+		}
+		// the aborts and the context errors caused by the timeout (ex: ctx.CheckErr(err)) are also a 503.
+		// other panics are bugs of handler: they are not hidden
+		if r := recover(); r != nil {
+			e, ok := r.(error)
+			if !ok || !(errors.Is(e, ErrHttpAbort) || errors.Is(e, context.DeadlineExceeded) || errors.Is(e, context.Canceled)) {
+				l.err.Printf("route '%s': panic after the timeout: %v", ctx.MatchInfo.Route.Name, r)
+				panic(r)
+			}
+		}
+		app.execHandlerError(ctx, 503)
+	}()
 	ctx.Next()
 }
 
+// returns the error handler of router of request, or of the app
+func (app *App) errHandler(ctx *Ctx, code int) Func {
+	if router := ctx.MatchInfo.Router; router != nil {
+		if h, ok := router.errHandlers[code]; ok {
+			return h
+		}
+	}
+	if h, ok := app.Router.errHandlers[code]; ok {
+		return h
+	}
+	return nil
+}
+
 func (app *App) execHandlerError(ctx *Ctx, code int) {
 	ctx.Reset()
-	if h, ok := app.errHandlers[code]; ok {
+	if h := app.errHandler(ctx, code); h != nil {
 		ctx.StatusCode = code
+		defer func() {
+			// ctx.JSON, ctx.HTML... inside of error handler
+			if err := recover(); err != nil {
+				if e, ok := err.(error); !ok || !errors.Is(e, ErrHttpAbort) {
+					panic(err)
+				}
+			}
+		}()
 		h(ctx)
+	} else if app.ProblemDetails {
+		ctx.writeProblem(NewProblem(code, ""))
 	} else {
 		ctx.StatusCode = code
 		statusText := http.StatusText(code)
@@ -555,14 +795,15 @@ func (app *App) closeConn(ctx *Ctx) {
 
 	rsp := ctx.Response
 	if err == nil {
+		execAfterRequest(ctx)
 		reqOK(ctx)
 		return
 	}
 	if e, ok := err.(error); ok && errors.Is(ErrHttpAbort, e) {
-		code := ctx.backCtx.Value(abortCode(1))
-		if c, ok := code.(int); ok {
+		if c, ok := ctx.takeAbortCode(); ok {
 			app.execHandlerError(ctx, c)
 		}
+		execAfterRequest(ctx)
 		reqOK(ctx)
 	} else {
 		rsp.StatusCode = 500
@@ -580,6 +821,10 @@ func (app *App) closeConn(ctx *Ctx) {
 //	app.UrlFor("index", false, "userID", "1"}) //  /users/1
 //	app.UrlFor("index", true, "userID", "1"}) // http://servername/users/1
 func (app *App) UrlFor(name string, external bool, args ...string) string {
+	return app.urlFor(nil, name, external, args...)
+}
+
+func (app *App) urlFor(tenant *Tenant, name string, external bool, args ...string) string {
 	var (
 		host   = ""
 		route  *Route
@@ -608,36 +853,61 @@ func (app *App) UrlFor(name string, external bool, args ...string) string {
 		}
 		params[args[i]] = args[i+1]
 	}
+	if tenant != nil {
+		for k, v := range tenant.UrlArgs {
+			if _, ok := params[k]; !ok {
+				params[k] = v
+			}
+		}
+	}
 
 	// Build Host
-	if external {
+	if external && tenant != nil && tenant.Host != "" {
+		schema := "http://"
+		if app.ListeningInTLS {
+			schema = "https://"
+		}
+		host = schema + tenant.Host
+		for _, h := range router.hosts {
+			for _, v := range h.vars {
+				delete(params, v.name)
+			}
+		}
+	} else if external {
 		schema := "http://"
 		if app.ListeningInTLS {
 			schema = "https://"
 		}
-		srvname := app.Servername
+		port := ""
 		if app.serverport != "" && (app.serverport != "80" && app.serverport != "443") {
-			srvname = net.JoinHostPort(app.Servername, app.serverport)
+			port = app.serverport
 		}
-		if router.Subdomain != "" {
-			host = schema + router.Subdomain + "." + srvname
+		if len(router.hosts) > 0 {
+			host = router.hosts[0].build(params)
+			if port != "" {
+				host = net.JoinHostPort(host, port)
+			}
+			host = schema + host
 		} else {
-			if app.Servername == "" {
-				_, p, _ := net.SplitHostPort(app.Srv.Addr)
-				h := net.JoinHostPort(localAddress, p)
-				host = schema + h
-			} else {
-				host = schema + srvname
+			_, p, _ := net.SplitHostPort(app.Srv.Addr)
+			h := net.JoinHostPort(localAddress, p)
+			host = schema + h
+		}
+	} else {
+		for _, h := range router.hosts {
+			for _, v := range h.vars {
+				delete(params, v.name) // the host variables are not in query
 			}
 		}
 	}
-	url := route.mountURI(args...)
+	url := route.mountURI(params)
 	return host + url
 }
 
 // http.Handler
 func (app *App) ServeHTTP(wr http.ResponseWriter, req *http.Request) {
 	ctx := NewCtx(app, wr, req)
+	defer ctx.Request.removeTempFiles()
 	defer app.closeConn(ctx)
 	app.execRoute(ctx)
 
@@ -0,0 +1,44 @@
+package braza
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseFormJSONNotObject(t *testing.T) {
+	app := newTestApp(t, nil, POST("/bulk", func(ctx *Ctx) {
+		ctx.TEXT(string(ctx.Request.ReadBody()), 200)
+	}))
+	for _, body := range []string{`[{"id":1},{"id":2}]`, `"text"`, `42`} {
+		rec := doRequest(app, "POST", "/bulk", body, "Content-Type", "application/json")
+		expectStatus(t, rec, http.StatusOK)
+		if rec.Body.String() != body {
+			t.Errorf("body: got %q, want %q", rec.Body.String(), body)
+		}
+	}
+}
+
+func TestParseFormMalformedJSON(t *testing.T) {
+	app := newTestApp(t, nil, POST("/", func(ctx *Ctx) { ctx.TEXT("ok", 200) }))
+	rec := doRequest(app, "POST", "/", `{"a":`, "Content-Type", "application/json")
+	expectStatus(t, rec, http.StatusBadRequest)
+}
+
+func TestMaxBodySize(t *testing.T) {
+	echo := func(ctx *Ctx) { ctx.TEXT(ctx.Request.ReadBody(), 200) }
+	small := POST("/small", echo)
+	small.Name = "small"
+	small.MaxBodySize = 4
+	unlimited := POST("/unlimited", echo)
+	unlimited.Name = "unlimited"
+	unlimited.MaxBodySize = -1
+	app := newTestApp(t, &Config{MaxBodySize: 10}, POST("/", echo), small, unlimited)
+
+	body := strings.Repeat("x", 20)
+	ct := []string{"Content-Type", "text/plain"}
+	expectStatus(t, doRequest(app, "POST", "/", "0123456789", ct...), http.StatusOK)
+	expectStatus(t, doRequest(app, "POST", "/", body, ct...), http.StatusRequestEntityTooLarge)
+	expectStatus(t, doRequest(app, "POST", "/small", "12345", ct...), http.StatusRequestEntityTooLarge)
+	expectStatus(t, doRequest(app, "POST", "/unlimited", body, ct...), http.StatusOK)
+}
@@ -0,0 +1,193 @@
+package braza
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v2"
+)
+
+/*
+Codec of a content type: decodes the request bodies (Request.Form and schemas)
+and encodes the responses of ctx.Render
+
+	"application/json"
+	"application/xml"
+	"application/yaml"
+	"application/msgpack"
+	"application/cbor"
+	"application/protobuf" // only proto.Message
+*/
+type Codec struct {
+	Marshal   func(v any) ([]byte, error)
+	Unmarshal func(data []byte, v any) error // v is a *map[string]any (Request.Form) or a pointer to schema
+}
+
+var cborDecMode, _ = cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]any(nil))}.DecMode()
+
+var codecs = map[string]*Codec{
+	"application/json": {Marshal: json.Marshal, Unmarshal: json.Unmarshal},
+	"application/xml": {
+		Marshal: xml.Marshal,
+		Unmarshal: func(data []byte, v any) error {
+			if m, ok := v.(*map[string]any); ok {
+				form, err := decodeXML(data)
+				if err == nil {
+					*m = form
+				}
+				return err
+			}
+			return xml.Unmarshal(data, v)
+		},
+	},
+	"application/yaml": {
+		Marshal: yaml.Marshal,
+		Unmarshal: func(data []byte, v any) error {
+			err := yaml.Unmarshal(data, v)
+			if m, ok := v.(*map[string]any); ok && err == nil {
+				normalizeYAML(*m)
+			}
+			return err
+		},
+	},
+	"application/msgpack": {
+		Marshal: func(v any) ([]byte, error) {
+			buf := &bytes.Buffer{}
+			enc := msgpack.NewEncoder(buf)
+			enc.SetCustomStructTag("json")
+			err := enc.Encode(v)
+			return buf.Bytes(), err
+		},
+		Unmarshal: func(data []byte, v any) error {
+			dec := msgpack.NewDecoder(bytes.NewReader(data))
+			dec.SetCustomStructTag("json")
+			return dec.Decode(v)
+		},
+	},
+	"application/cbor": {Marshal: cbor.Marshal, Unmarshal: cborDecMode.Unmarshal},
+	"application/protobuf": {
+		Marshal: func(v any) ([]byte, error) {
+			if m, ok := v.(proto.Message); ok {
+				return proto.Marshal(m)
+			}
+			return nil, fmt.Errorf("protobuf: '%T' is not a proto.Message", v)
+		},
+		Unmarshal: func(data []byte, v any) error {
+			if m, ok := v.(proto.Message); ok {
+				return proto.Unmarshal(data, m)
+			}
+			return errors.ErrUnsupported // the Form: the body is decoded only in the schema
+		},
+	},
+}
+
+// the order of preference of codecs (Accept: */*)
+var codecTypes = []string{
+	"application/json",
+	"application/xml",
+	"application/yaml",
+	"application/msgpack",
+	"application/cbor",
+	"application/protobuf",
+}
+
+// other names of content types
+var codecAliases = map[string]string{
+	"text/json":               "application/json",
+	"text/xml":                "application/xml",
+	"text/yaml":               "application/yaml",
+	"application/x-yaml":      "application/yaml",
+	"application/x-msgpack":   "application/msgpack",
+	"application/vnd.msgpack": "application/msgpack",
+	"application/x-protobuf":  "application/protobuf",
+}
+
+/*
+Register a codec of content type (or replace a builtin codec)
+
+	app.RegisterCodec("application/toml", toml.Marshal, toml.Unmarshal)
+
+	func index(ctx *braza.Ctx) {
+		ctx.Render(data, 200) // Accept: application/toml
+	}
+*/
+func (app *App) RegisterCodec(contentType string, marshal func(any) ([]byte, error), unmarshal func([]byte, any) error) {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		panic(fmt.Errorf("codec '%s' has a invalid content type: %v", contentType, err))
+	}
+	if app.codecs == nil {
+		app.codecs = map[string]*Codec{}
+	}
+	if _, ok := app.getCodec(mt); !ok {
+		app.codecTypes = append(app.codecTypes, mt)
+	}
+	app.codecs[mt] = &Codec{Marshal: marshal, Unmarshal: unmarshal}
+}
+
+// returns the codec of media type (with the aliases and suffixes, ex: 'application/problem+json')
+func (app *App) getCodec(mediaType string) (*Codec, bool) {
+	mediaType = strings.ToLower(mediaType)
+	if alias, ok := codecAliases[mediaType]; ok {
+		mediaType = alias
+	}
+	if c, ok := app.codecs[mediaType]; ok {
+		return c, true
+	}
+	if c, ok := codecs[mediaType]; ok {
+		return c, true
+	}
+	if i := strings.LastIndex(mediaType, "+"); i > 0 {
+		return app.getCodec("application/" + mediaType[i+1:])
+	}
+	return nil, false
+}
+
+// the content types of codecs, by order of preference (the aliases are the last)
+func (app *App) getCodecTypes() []string {
+	types := append(slices.Clone(codecTypes), app.codecTypes...)
+	for alias := range codecAliases {
+		types = append(types, alias)
+	}
+	slices.Sort(types[len(types)-len(codecAliases):])
+	return types
+}
+
+/*
+Write the body with the codec of header 'Accept' (json, xml, yaml, msgpack, cbor, protobuf
+or a custom codec) and abort. Without a acceptable codec, responds 406 Not Acceptable
+
+	func getUser(ctx *braza.Ctx) {
+		user := db.FindUser(...)
+		ctx.Render(user, 200)
+		// Accept: application/json -> {"id":1,"name":"joe"}
+		// Accept: application/yaml -> id: 1\nname: joe
+	}
+*/
+func (r *Response) Render(body any, code int) {
+	app := r.ctx.App
+	r.header.Add("Vary", "Accept")
+	ctype := bestMediaType(r.ctx.Request.Header.Get("Accept"), app.getCodecTypes())
+	if ctype == "" {
+		r.Abort(406)
+	}
+	codec, _ := app.getCodec(ctype)
+	b, err := codec.Marshal(r.filterBody(body, code))
+	r.CheckErr(err)
+
+	r.Reset()
+	r.StatusCode = code
+	r.header.Set("Content-Type", ctype)
+	r.Write(b)
+	panic(ErrHttpAbort)
+}
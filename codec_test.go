@@ -0,0 +1,95 @@
+package braza
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type codecUser struct {
+	ID   int    `json:"id" yaml:"id" xml:"id"`
+	Name string `json:"name" yaml:"name" xml:"name"`
+}
+
+func TestRenderCodecs(t *testing.T) {
+	app := newTestApp(t, nil, GET("/", func(ctx *Ctx) { ctx.Render(&codecUser{1, "joe"}, 200) }))
+
+	cases := []struct{ accept, ctype, body string }{
+		{"", "application/json", `{"id":1,"name":"joe"}`},
+		{"application/json", "application/json", `{"id":1,"name":"joe"}`},
+		{"application/yaml", "application/yaml", "id: 1\nname: joe\n"},
+		{"text/html;q=0.9, application/xml", "application/xml", "<codecUser><id>1</id><name>joe</name></codecUser>"},
+	}
+	for _, c := range cases {
+		rec := doRequest(app, "GET", "/", "", "Accept", c.accept)
+		expectStatus(t, rec, 200)
+		if ct := rec.Header().Get("Content-Type"); ct != c.ctype || rec.Body.String() != c.body {
+			t.Errorf("Accept %q: got %s %q, want %s %q", c.accept, ct, rec.Body.String(), c.ctype, c.body)
+		}
+	}
+
+	rec := doRequest(app, "GET", "/", "", "Accept", "application/msgpack")
+	var u codecUser
+	dec := msgpack.NewDecoder(bytes.NewReader(rec.Body.Bytes()))
+	dec.SetCustomStructTag("json")
+	if err := dec.Decode(&u); err != nil || u != (codecUser{1, "joe"}) {
+		t.Errorf("msgpack: %+v, %v", u, err)
+	}
+
+	expectStatus(t, doRequest(app, "GET", "/", "", "Accept", "image/png"), 406)
+}
+
+func TestFormCodecs(t *testing.T) {
+	var form map[string]any
+	app := newTestApp(t, nil, POST("/", func(ctx *Ctx) {
+		form = ctx.Request.Form
+		ctx.NoContent()
+	}))
+	msgpackBody, _ := msgpack.Marshal(map[string]any{"name": "joe"})
+
+	cases := []struct{ ctype, body string }{
+		{"application/json", `{"name":"joe"}`},
+		{"application/yaml", "name: joe\n"},
+		{"application/x-yaml", "name: joe\n"},
+		{"application/xml", "<user><name>joe</name></user>"},
+		{"application/msgpack", string(msgpackBody)},
+		{"application/vnd.api+json", `{"name":"joe"}`},
+	}
+	for _, c := range cases {
+		form = nil
+		expectStatus(t, doRequest(app, "POST", "/", c.body, "Content-Type", c.ctype), 204)
+		if form["name"] != "joe" {
+			t.Errorf("%s: form %#v", c.ctype, form)
+		}
+	}
+}
+
+func TestRegisterCodec(t *testing.T) {
+	app := NewApp(&Config{DisableStatic: true})
+	app.RegisterCodec("text/csv",
+		func(v any) ([]byte, error) {
+			u := v.(*codecUser)
+			return []byte(fmt.Sprintf("%d,%s", u.ID, u.Name)), nil
+		},
+		func(data []byte, v any) error {
+			id, name, _ := strings.Cut(string(data), ",")
+			*(v.(*map[string]any)) = map[string]any{"id": id, "name": name}
+			return nil
+		},
+	)
+	var form map[string]any
+	app.POST("/", func(ctx *Ctx) {
+		form = ctx.Request.Form
+		ctx.Render(&codecUser{1, "joe"}, 200)
+	})
+	app.Build()
+
+	rec := doRequest(app, "POST", "/", "2,ann", "Content-Type", "text/csv", "Accept", "text/csv")
+	expectStatus(t, rec, 200)
+	if rec.Body.String() != "1,joe" || form["name"] != "ann" {
+		t.Errorf("custom codec: body %q, form %#v", rec.Body.String(), form)
+	}
+}
@@ -35,32 +35,60 @@ type Config struct {
 	/*
 
 	 */
-	Env            string // environmnt (default 'development')
-	SecretKey      string // for sign session (default '')
-	Servername     string // for build url routes and route match (default '')
-	ListeningInTLS bool   // UrlFor return a URl with schema in "https:" (default 'false')
+	Env            string   // environmnt (default 'development')
+	SecretKey      string   // for sign session (default '')
+	Servername     string   // for build url routes and route match (default '')
+	Servernames    []string // other servernames of app (ex: "example.org", "example.com.br"). The Servername is used in UrlFor (default nil)
+	ListeningInTLS bool     // UrlFor return a URl with schema in "https:" (default 'false')
 
 	TemplateFolder          string // for render Templates Html. Default "templates/"
 	TemplateFuncs           template.FuncMap
-	DisableParseFormBody    bool // Disable default parse of Request.Form -> if true, use Request.ParseForm()
+	DisableParseFormBody    bool // Disable default parse of Request.Form (before the route func) -> if true, use Request.ParseForm()
 	DisableTemplateReloader bool // if app in dev mode, disable template's reload (default false)
+	LiveReload              bool // if app in dev mode, reload the browser when templates or static files change (default false)
 
 	StaticFolder  string // for serve static files (default '/assets')
 	StaticUrlPath string // url uf request static file (default '/assets')
 	DisableStatic bool   // disable static endpoint for serving static files (default false)
 
+	MaxBodySize     int64 // max size of request body (not multipart), larger bodies are a 413 Request Entity Too Large. Route.MaxBodySize overrides it (default 32 MB, -1 is unlimited)
+	MaxUploadSize   int64 // max size of a multipart body, with all files (413). Route.MaxBodySize overrides it (default 1 GB, -1 is unlimited)
+	MaxFileSize     int64 // max size of each uploaded file (413) (default 0, only the MaxUploadSize)
+	MaxPartSize     int64 // max size of each multipart field that is not a file (413) (default 10 MB)
+	MaxParts        int   // max number of parts (files and fields) of a multipart body (413) (default 1000, -1 is unlimited)
+	MultipartMemory int64 // the uploaded files larger than this are written in temp files (default 10 MB)
+
+	ProblemDetails bool // render the http errors (aborts, schema errors...) as 'application/problem+json' (RFC 9457) (default false)
+
+	// the json bodies are filtered by the RespSchema of route for the status code: the fields not declared are dropped.
+	// a field with a type that doesn't match the schema is a 500 Internal Server Error (default false)
+	EnforceRespSchema bool
+
 	Silent             bool   // don't print logs (default false)
 	LogFile            string // save log info in file (default '')
 	DotenvFileName     string
 	DisableFileWatcher bool // disable autoreload in dev mode (default false)
 
+	WatcherInclude  []string      // glob patterns of files that reload the server (default go files, go.mod, .env and config files)
+	WatcherExclude  []string      // glob patterns of files and dirs ignored by the watcher (default .git, vendor, node_modules, tests...)
+	WatcherDebounce time.Duration // wait for more changes before reload (default 300 milliseconds)
+
+	EnableH2C   bool // serve HTTP/2 without TLS (h2c), for internal traffic behind proxies (default false)
+	EnableHTTP3 bool // with ListenTLS, also serve HTTP/3 (QUIC) on the same port and send 'Alt-Svc' (default false)
+
+	DisableGracefulReload bool          // disable the binary reload on SIGHUP/SIGUSR2 out of dev mode (default false)
+	GracefulTimeout       time.Duration // max time to wait the new process and drain the connections (default 30 seconds)
+
 	SessionExpires          time.Duration // (default 30 minutes)
 	SessionPermanentExpires time.Duration // (default 31 days)
 
 	SessionPublicKey  *rsa.PublicKey
 	SessionPrivateKey *rsa.PrivateKey
 
+	CookieDefaults *CookieOptions // options of signed, encrypted and typed cookies (default Path '/', HttpOnly and SameSite Lax)
+
 	serverport        string
+	servernames       []string // Servername and Servernames, normalized
 	defaultWsUpgrader *websocket.Upgrader
 }
 
@@ -82,6 +110,27 @@ func (c *Config) checkConfig() {
 			c.StaticUrlPath = "/assets"
 		}
 	}
+	if c.MaxBodySize == 0 {
+		c.MaxBodySize = 32 << 20
+	}
+	if c.MaxUploadSize == 0 {
+		c.MaxUploadSize = 1 << 30
+	}
+	if c.MaxPartSize == 0 {
+		c.MaxPartSize = 10 << 20
+	}
+	if c.MaxParts == 0 {
+		c.MaxParts = 1000
+	}
+	if c.MultipartMemory == 0 {
+		c.MultipartMemory = defaultMaxMemory
+	}
+	if c.WatcherDebounce == 0 {
+		c.WatcherDebounce = time.Millisecond * 300
+	}
+	if c.GracefulTimeout == 0 {
+		c.GracefulTimeout = time.Second * 30
+	}
 	if c.SessionExpires == 0 {
 		c.SessionExpires = time.Minute * 30
 	}
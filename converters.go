@@ -0,0 +1,201 @@
+package braza
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+/*
+Converter of route variables: validates the value in the match and formats it in UrlFor
+
+	"/{id:int}"        // 123
+	"/{price:float}"   // 12.5
+	"/{id:uuid}"       // 6ba7b810-9dad-11d1-80b4-00c04fd430c8
+	"/{title:slug}"    // hello-world
+	"/{code:re:[a-z]{3}}" // custom regex (slashes are not allowed)
+*/
+type Converter struct {
+	Pattern string                    // regex of url segment, without '^' and '$'
+	Parse   func(string) (any, error) // validates the value of url. if nil, the value is the string
+	Format  func(any) string          // formats the value in UrlFor. if nil, uses fmt.Sprint
+}
+
+var converters = map[string]*Converter{
+	"str": {Pattern: `[^/]+`},
+	"int": {
+		Pattern: `\d+`,
+		Parse:   func(s string) (any, error) { return strconv.Atoi(s) },
+	},
+	"float": {
+		Pattern: `[-+]?\d+(\.\d+)?`,
+		Parse:   func(s string) (any, error) { return strconv.ParseFloat(s, 64) },
+		Format: func(v any) string {
+			if f, ok := v.(float64); ok {
+				return strconv.FormatFloat(f, 'f', -1, 64)
+			}
+			return fmt.Sprint(v)
+		},
+	},
+	"uuid": {
+		Pattern: `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+		Parse:   func(s string) (any, error) { return uuid.Parse(s) },
+	},
+	"slug": {Pattern: `[a-z0-9]+(?:-[a-z0-9]+)*`},
+}
+
+/*
+Register a custom converter of route variables
+
+	app.RegisterConverter("date", `\d{4}-\d{2}-\d{2}`,
+		func(s string) (any, error) { return time.Parse(time.DateOnly, s) },
+		func(v any) string { return v.(time.Time).Format(time.DateOnly) },
+	)
+	app.GET("/posts/{day:date}", posts)
+
+	func posts(ctx *braza.Ctx) {
+		day := ctx.Request.PathValues["day"].(time.Time)
+		...
+	}
+*/
+func (app *App) RegisterConverter(name, pattern string, parse func(string) (any, error), format func(any) string) {
+	if app.converters == nil {
+		app.converters = map[string]*Converter{}
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		panic(fmt.Errorf("converter '%s' has a invalid pattern: %v", name, err))
+	}
+	app.converters[name] = &Converter{Pattern: pattern, Parse: parse, Format: format}
+}
+
+func (app *App) getConverter(name string) (*Converter, bool) {
+	if c, ok := app.converters[name]; ok {
+		return c, true
+	}
+	c, ok := converters[name]
+	return c, ok
+}
+
+// a segment of route url: literal ('users', or a raw regex) or variable ('{id:int}')
+type urlSegment struct {
+	name     string
+	conv     *Converter
+	regex    *regexp.Regexp
+	isVar    bool
+	isPath   bool   // {name:path} or {name:*}, matches the rest of url
+	optional bool   // {name?} or {name=default}
+	def      string // the default value, formatted by the converter
+	defValue any    // the default value, parsed by the converter
+}
+
+// returns the value of a variable segment (and the string formatted by the converter)
+func (s *urlSegment) parse(str string) (any, string, error) {
+	if s.conv == nil || s.conv.Parse == nil {
+		return str, str, nil
+	}
+	v, err := s.conv.Parse(str)
+	if err != nil {
+		return nil, "", err
+	}
+	if s.conv.Format != nil {
+		return v, s.conv.Format(v), nil
+	}
+	return v, fmt.Sprint(v), nil
+}
+
+func isUrlVar(str string) bool {
+	if len(str) < 3 || str[0] != '{' || str[len(str)-1] != '}' {
+		return false
+	}
+	c := str[1]
+	return c == '_' || c == '*' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+var reVarName = regexp.MustCompile(`^(\w+|\*)$`)
+
+/*
+parse a url segment:
+
+	{name}              // same as {name:str}
+	{name:int}          // converter
+	{name:re:[a-z]{3}}  // converter with argument
+	{name:path}, {name:*}, {*}
+	{name?}, {name:int?}  // optional
+	{name=foo}, {name:int=1}  // optional with default value
+	{name?:re:[a-z]+}, {name=abc:re:[a-z]+} // in 're', the modifiers go in the name
+*/
+func (app *App) parseUrlSegment(routeName, str string) *urlSegment {
+	if !isUrlVar(str) {
+		return &urlSegment{name: str, regex: regexp.MustCompile("^" + str + "$")}
+	}
+	name, rest, _ := strings.Cut(str[1:len(str)-1], ":")
+	convName, arg, _ := strings.Cut(rest, ":")
+
+	seg := &urlSegment{isVar: true}
+	hasDef := false
+	name, seg.def, hasDef = seg.cutModifiers(name)
+	if convName != "re" {
+		var ok bool
+		convName, seg.def, ok = seg.cutModifiers(convName)
+		hasDef = hasDef || ok
+	}
+	if !reVarName.MatchString(name) {
+		panic(fmt.Errorf("Route '%s' has a invalid variable: '%s'", routeName, str))
+	}
+	seg.name = name
+	switch convName {
+	case "":
+		seg.conv = converters["str"]
+	case "*", "path":
+		return seg.pathSegment(hasDef)
+	case "re":
+		if arg == "" {
+			panic(fmt.Errorf("Route '%s': the converter 're' needs a regex: '%s'", routeName, str))
+		}
+		seg.conv = &Converter{Pattern: arg}
+	default:
+		c, ok := app.getConverter(convName)
+		if !ok {
+			panic(fmt.Errorf("Route '%s' has a unknown converter: '%s'", routeName, str))
+		}
+		seg.conv = c
+	}
+	if name == "*" {
+		return seg.pathSegment(hasDef)
+	}
+	seg.regex = regexp.MustCompile("^(" + seg.conv.Pattern + ")$")
+	if hasDef {
+		v, def, err := seg.parse(seg.def)
+		if err != nil || !seg.regex.MatchString(seg.def) {
+			panic(fmt.Errorf("Route '%s' has a invalid default value: '%s'", routeName, str))
+		}
+		seg.def, seg.defValue = def, v
+	}
+	return seg
+}
+
+func (s *urlSegment) pathSegment(hasDef bool) *urlSegment {
+	s.isPath = true
+	if hasDef {
+		s.def = "/" + strings.TrimPrefix(s.def, "/")
+		s.defValue = s.def
+	}
+	return s
+}
+
+// 'page?' -> 'page', optional; 'page=1' -> 'page', optional, default '1'
+func (s *urlSegment) cutModifiers(str string) (string, string, bool) {
+	if name, def, ok := strings.Cut(str, "="); ok {
+		s.optional = true
+		return name, def, true
+	}
+	if strings.HasSuffix(str, "?") {
+		s.optional = true
+		return strings.TrimSuffix(str, "?"), s.def, false
+	}
+	return str, s.def, false
+}
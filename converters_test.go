@@ -0,0 +1,127 @@
+package braza
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func showPathValue(ctx *Ctx) {
+	for k, v := range ctx.Request.PathValues {
+		ctx.TEXT(fmt.Sprintf("%s=%T:%v", k, v, v), 200)
+	}
+	ctx.TEXT("", 200)
+}
+
+func TestConverters(t *testing.T) {
+	app := NewApp(&Config{DisableStatic: true})
+	app.RegisterConverter("date", `\d{4}-\d{2}-\d{2}`,
+		func(s string) (any, error) { return time.Parse(time.DateOnly, s) },
+		func(v any) string { return v.(time.Time).Format(time.DateOnly) },
+	)
+	routes := map[string]string{
+		"users":  "/users/{id:int}",
+		"prices": "/prices/{price:float}",
+		"items":  "/items/{id:uuid}",
+		"posts":  "/posts/{title:slug}",
+		"codes":  "/codes/{code:re:[a-z]{3}}",
+		"days":   "/days/{day:date}",
+	}
+	for name, url := range routes {
+		r := GET(url, showPathValue)
+		r.Name = name
+		app.AddRoute(r)
+	}
+	app.Build()
+
+	cases := []struct {
+		url  string
+		code int
+		body string
+	}{
+		{"/users/42", 200, "id=int:42"},
+		{"/users/abc", 404, ""},
+		{"/prices/12.5", 200, "price=float64:12.5"},
+		{"/prices/1.2.3", 404, ""},
+		{"/items/6ba7b810-9dad-11d1-80b4-00c04fd430c8", 200, "id=uuid.UUID:6ba7b810-9dad-11d1-80b4-00c04fd430c8"},
+		{"/items/6ba7b810", 404, ""},
+		{"/posts/hello-world", 200, "title=string:hello-world"},
+		{"/posts/Hello_World", 404, ""},
+		{"/codes/abc", 200, "code=string:abc"},
+		{"/codes/abcd", 404, ""},
+		{"/days/2024-02-29", 200, "day=time.Time:2024-02-29 00:00:00 +0000 UTC"},
+		{"/days/2023-02-29", 404, ""}, // matches the pattern, but Parse fails
+	}
+	for _, c := range cases {
+		rec := doRequest(app, "GET", c.url, "")
+		if rec.Code != c.code || (c.code == 200 && rec.Body.String() != c.body) {
+			t.Errorf("%s: got %d %q, want %d %q", c.url, rec.Code, rec.Body.String(), c.code, c.body)
+		}
+	}
+
+	if url := app.UrlFor("days", false, "day", "2024-01-02"); url != "/days/2024-01-02" {
+		t.Errorf("UrlFor: %s", url)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("UrlFor with a value that doesn't match the converter must panic")
+			}
+		}()
+		app.UrlFor("users", false, "id", "abc")
+	}()
+}
+
+func TestUnknownConverter(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("a unknown converter must panic in the build")
+		}
+	}()
+	newTestApp(t, nil, GET("/{id:nope}", showPathValue))
+}
+
+func TestOptionalSegments(t *testing.T) {
+	page := GET("/posts/{page:int=1}", showPathValue)
+	page.Name = "posts"
+	lang := GET("/docs/{lang?}", showPathValue)
+	lang.Name = "docs"
+	files := GET("/files/{path:*}", showPathValue)
+	files.Name = "files"
+	app := newTestApp(t, nil, page, lang, files)
+
+	cases := []struct {
+		url  string
+		code int
+		body string
+	}{
+		{"/posts", 200, "page=int:1"},
+		{"/posts/3", 200, "page=int:3"},
+		{"/posts/x", 404, ""},
+		{"/docs/pt", 200, "lang=string:pt"},
+		{"/docs", 200, ""},
+		{"/files/a/b/c.txt", 200, "path=string:/a/b/c.txt"},
+	}
+	for _, c := range cases {
+		rec := doRequest(app, "GET", c.url, "")
+		if rec.Code != c.code || (c.code == 200 && rec.Body.String() != c.body) {
+			t.Errorf("%s: got %d %q, want %d %q", c.url, rec.Code, rec.Body.String(), c.code, c.body)
+		}
+	}
+
+	if url := app.UrlFor("posts", false); url != "/posts" {
+		t.Errorf("UrlFor without the optional: %s", url)
+	}
+	if url := app.UrlFor("posts", false, "page", "2"); url != "/posts/2" {
+		t.Errorf("UrlFor with the optional: %s", url)
+	}
+}
+
+func TestOptionalSegmentsMustBeLast(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("a required segment after a optional one must panic")
+		}
+	}()
+	newTestApp(t, nil, GET("/{lang?}/docs", showPathValue))
+}
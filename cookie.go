@@ -0,0 +1,251 @@
+package braza
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errCookieSecret = errors.New("to use signed and encrypted cookies, you need to set a 'App.SecretKey'")
+
+/*
+Options of cookies set by ctx.SetSignedCookie, ctx.SetEncryptedCookie and braza.Cookie[T].
+The defaults are App.CookieDefaults, or:
+
+	&braza.CookieOptions{Path: "/", HttpOnly: true, SameSite: http.SameSiteLaxMode}
+*/
+type CookieOptions struct {
+	Path     string
+	Domain   string
+	MaxAge   time.Duration // 0 is a session cookie (removed when the browser closes). the signed and encrypted values expire too
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+}
+
+var defaultCookieOptions = &CookieOptions{Path: "/", HttpOnly: true, SameSite: http.SameSiteLaxMode}
+
+func (ctx *Ctx) cookieOptions(opts []*CookieOptions) *CookieOptions {
+	o := ctx.App.CookieDefaults
+	if len(opts) > 0 && opts[0] != nil {
+		o = opts[0]
+	}
+	if o == nil {
+		o = defaultCookieOptions
+	}
+	return o
+}
+
+// the expiry (unix seconds) in the signed and encrypted values: a captured cookie can't be
+// used after the MaxAge. 0 is a session cookie (without expiry)
+func (ctx *Ctx) cookieExpiry(opts []*CookieOptions) int64 {
+	if o := ctx.cookieOptions(opts); o.MaxAge > 0 {
+		return time.Now().Add(o.MaxAge).Unix()
+	}
+	return 0
+}
+
+func cookieExpired(exp int64) bool { return exp != 0 && time.Now().Unix() > exp }
+
+func (ctx *Ctx) newCookie(name, value string, opts []*CookieOptions) *http.Cookie {
+	o := ctx.cookieOptions(opts)
+	c := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     o.Path,
+		Domain:   o.Domain,
+		Secure:   o.Secure,
+		HttpOnly: o.HttpOnly,
+		SameSite: o.SameSite,
+	}
+	if o.MaxAge > 0 {
+		c.MaxAge = int(o.MaxAge.Seconds())
+		c.Expires = time.Now().Add(o.MaxAge)
+	} else if o.MaxAge < 0 {
+		c.MaxAge = -1
+	}
+	return c
+}
+
+// the keys of cookies are derived from the SecretKey (of tenant or app)
+func (ctx *Ctx) cookieKey(purpose string) []byte {
+	secret, _, _ := ctx.sessionKeys()
+	if secret == "" {
+		panic(errCookieSecret)
+	}
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte("braza-cookie:" + purpose))
+	return h.Sum(nil)
+}
+
+func (ctx *Ctx) signCookie(name, value, exp string) []byte {
+	h := hmac.New(sha256.New, ctx.cookieKey("sign"))
+	h.Write([]byte(name + "=" + value + "|" + exp)) // the name is signed too: the value is not valid in other cookie
+	return h.Sum(nil)
+}
+
+/*
+Set a cookie signed with HMAC-SHA256: the client can read the value, but can't change it.
+The expiry of MaxAge is signed with the value, a expired cookie is invalid
+
+	ctx.SetSignedCookie("user_id", "42")
+	ctx.SetSignedCookie("theme", "dark", &braza.CookieOptions{Path: "/", MaxAge: time.Hour * 24 * 365})
+*/
+func (ctx *Ctx) SetSignedCookie(name, value string, opts ...*CookieOptions) {
+	enc := base64.RawURLEncoding
+	exp := strconv.FormatInt(ctx.cookieExpiry(opts), 36)
+	v := enc.EncodeToString([]byte(value)) + "." + exp + "." + enc.EncodeToString(ctx.signCookie(name, value, exp))
+	ctx.SetCookie(ctx.newCookie(name, v, opts))
+}
+
+// Returns the value of a signed cookie. False if the cookie does not exist, the signature is invalid or is expired
+//
+//	userID, ok := ctx.SignedCookie("user_id")
+func (ctx *Ctx) SignedCookie(name string) (string, bool) {
+	c, ok := ctx.Request.Cookies[name]
+	if !ok {
+		return "", false
+	}
+	enc := base64.RawURLEncoding
+	parts := strings.Split(c.Value, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+	value, err1 := enc.DecodeString(parts[0])
+	mac, err2 := enc.DecodeString(parts[2])
+	if err1 != nil || err2 != nil || !hmac.Equal(mac, ctx.signCookie(name, string(value), parts[1])) {
+		return "", false
+	}
+	if exp, err := strconv.ParseInt(parts[1], 36, 64); err != nil || cookieExpired(exp) {
+		return "", false
+	}
+	return string(value), true
+}
+
+func (ctx *Ctx) cookieCipher() cipher.AEAD {
+	block, err := aes.NewCipher(ctx.cookieKey("encrypt"))
+	if err != nil {
+		panic(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return gcm
+}
+
+/*
+Set a cookie encrypted with AES-GCM: the client can't read or change the value.
+The expiry of MaxAge is sealed with the value, a expired cookie is invalid
+
+	ctx.SetEncryptedCookie("token", apiToken)
+*/
+func (ctx *Ctx) SetEncryptedCookie(name, value string, opts ...*CookieOptions) {
+	gcm := ctx.cookieCipher()
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(err)
+	}
+	plain := binary.BigEndian.AppendUint64(nil, uint64(ctx.cookieExpiry(opts)))
+	sealed := gcm.Seal(nonce, nonce, append(plain, value...), []byte(name))
+	ctx.SetCookie(ctx.newCookie(name, base64.RawURLEncoding.EncodeToString(sealed), opts))
+}
+
+// Returns the value of a encrypted cookie. False if the cookie does not exist, can't be decrypted or is expired
+//
+//	token, ok := ctx.EncryptedCookie("token")
+func (ctx *Ctx) EncryptedCookie(name string) (string, bool) {
+	c, ok := ctx.Request.Cookies[name]
+	if !ok {
+		return "", false
+	}
+	b, err := base64.RawURLEncoding.DecodeString(c.Value)
+	if err != nil {
+		return "", false
+	}
+	gcm := ctx.cookieCipher()
+	if len(b) < gcm.NonceSize() {
+		return "", false
+	}
+	plain, err := gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], []byte(name))
+	if err != nil || len(plain) < 8 || cookieExpired(int64(binary.BigEndian.Uint64(plain))) {
+		return "", false
+	}
+	return string(plain[8:]), true
+}
+
+// Remove a cookie of client (with the Path and Domain of options)
+func (ctx *Ctx) DeleteCookie(name string, opts ...*CookieOptions) {
+	c := ctx.newCookie(name, "", opts)
+	c.MaxAge = -1
+	c.Expires = time.Unix(0, 0)
+	ctx.SetCookie(c)
+}
+
+/*
+A typed cookie: the value is encoded in json and signed (or encrypted)
+
+	type Cart struct {
+		Items []int `json:"items"`
+	}
+
+	var cartCookie = braza.Cookie[Cart]{Name: "cart", Encrypted: true}
+
+	func addItem(ctx *braza.Ctx) {
+		cart, _ := cartCookie.Get(ctx)
+		cart.Items = append(cart.Items, 42)
+		cartCookie.Set(ctx, cart)
+		...
+	}
+*/
+type Cookie[T any] struct {
+	Name      string
+	Encrypted bool           // encrypted with AES-GCM, else signed with HMAC-SHA256
+	Options   *CookieOptions // default is App.CookieDefaults
+}
+
+// Returns the value of cookie. False if the cookie does not exist, is invalid or can't be decoded
+func (c Cookie[T]) Get(ctx *Ctx) (T, bool) {
+	var (
+		v   T
+		raw string
+		ok  bool
+	)
+	if c.Encrypted {
+		raw, ok = ctx.EncryptedCookie(c.Name)
+	} else {
+		raw, ok = ctx.SignedCookie(c.Name)
+	}
+	if !ok || json.Unmarshal([]byte(raw), &v) != nil {
+		return v, false
+	}
+	return v, true
+}
+
+// Set the value of cookie
+func (c Cookie[T]) Set(ctx *Ctx, v T) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	if c.Encrypted {
+		ctx.SetEncryptedCookie(c.Name, string(b), c.Options)
+	} else {
+		ctx.SetSignedCookie(c.Name, string(b), c.Options)
+	}
+}
+
+// Remove the cookie of client
+func (c Cookie[T]) Del(ctx *Ctx) {
+	ctx.DeleteCookie(c.Name, c.Options)
+}
@@ -0,0 +1,79 @@
+package braza
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type cookieCart struct {
+	Items []int `json:"items"`
+}
+
+var testCartCookie = Cookie[cookieCart]{Name: "cart", Encrypted: true}
+
+func TestCookies(t *testing.T) {
+	set := GET("/set", func(ctx *Ctx) {
+		ctx.SetSignedCookie("user_id", "42")
+		ctx.SetEncryptedCookie("token", "s3cret", &CookieOptions{Path: "/", MaxAge: time.Hour})
+		testCartCookie.Set(ctx, cookieCart{Items: []int{1, 2}})
+		ctx.NoContent()
+	})
+	set.Name = "set"
+	var got []string
+	get := GET("/get", func(ctx *Ctx) {
+		user, ok1 := ctx.SignedCookie("user_id")
+		token, ok2 := ctx.EncryptedCookie("token")
+		cart, ok3 := testCartCookie.Get(ctx)
+		got = []string{user, strconv.FormatBool(ok1), token, strconv.FormatBool(ok2), strconv.FormatBool(ok3 && len(cart.Items) == 2)}
+		ctx.NoContent()
+	})
+	get.Name = "get"
+	app := newTestApp(t, &Config{SecretKey: "test-secret"}, set, get)
+
+	rec := doRequest(app, "GET", "/set", "")
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 3 {
+		t.Fatalf("all the Set-Cookie headers must be kept: %v", rec.Header()["Set-Cookie"])
+	}
+	header := []string{}
+	for _, c := range cookies {
+		if c.Name == "token" && strings.Contains(c.Value, "s3cret") {
+			t.Error("the encrypted cookie is in plain text")
+		}
+		if c.Name == "token" && c.MaxAge != 3600 {
+			t.Errorf("MaxAge: %d", c.MaxAge)
+		}
+		header = append(header, c.Name+"="+c.Value)
+	}
+
+	doRequest(app, "GET", "/get", "", "Cookie", strings.Join(header, "; "))
+	if want := "42,true,s3cret,true,true"; strings.Join(got, ",") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+
+	// a value changed by the client: "42" -> "43", with the signature of "42"
+	_, sig, _ := strings.Cut(cookies[0].Value, ".")
+	tampered := base64.RawURLEncoding.EncodeToString([]byte("43")) + "." + sig
+	doRequest(app, "GET", "/get", "", "Cookie", "user_id="+tampered)
+	if got[1] != "false" {
+		t.Errorf("the tampered cookie was accepted: %v", got)
+	}
+}
+
+func TestSignedCookieExpired(t *testing.T) {
+	app := newTestApp(t, &Config{SecretKey: "test-secret"}, GET("/", func(ctx *Ctx) {
+		enc := base64.RawURLEncoding
+		exp := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 36)
+		v := enc.EncodeToString([]byte("42")) + "." + exp + "." + enc.EncodeToString(ctx.signCookie("user_id", "42", exp))
+		ctx.Request.Cookies["user_id"] = &http.Cookie{Name: "user_id", Value: v}
+		if _, ok := ctx.SignedCookie("user_id"); ok {
+			t.Error("a expired cookie was accepted")
+		}
+		ctx.NoContent()
+	}))
+	expectStatus(t, doRequest(app, "GET", "/", ""), 204)
+}
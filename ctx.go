@@ -2,15 +2,15 @@ package braza
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"slices"
+	"time"
 
 	"github.com/ethoDomingues/c3po"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-type abortCode int
-
 // Returns a new *braza.Ctx
 func NewCtx(app *App, wr http.ResponseWriter, rq *http.Request) *Ctx {
 	ctx := &Ctx{
@@ -20,15 +20,12 @@ func NewCtx(app *App, wr http.ResponseWriter, rq *http.Request) *Ctx {
 			del:    []string{},
 			claims: jwt.MapClaims{},
 		},
-		MatchInfo: &MatchInfo{},
+		MatchInfo:  &MatchInfo{},
+		midCounter: -1,
 	}
 
 	ctx.Request = NewRequest(rq, ctx)
 	ctx.Response = NewResponse(wr, ctx)
-
-	c := context.Background()
-	ctx.backCtx = context.WithValue(c, abortCode(1), nil)
-
 	return ctx
 }
 
@@ -122,6 +119,8 @@ type Ctx struct {
 	Schema        Schema
 	SchemaFielder *c3po.Fielder
 
+	respSchema RespSchema // RespSchema of route method, for Config.EnforceRespSchema
+
 	/*
 		Contains information about the current request, route, etc...
 
@@ -134,10 +133,49 @@ type Ctx struct {
 	*/
 	MatchInfo *MatchInfo
 
+	/*
+		Tenant of request, resolved by App.TenantResolver (nil if the app doesn't have tenants)
+			func index(ctx *braza.Ctx) {
+				shop := ctx.Tenant.Data.(*Shop)
+				...
+			}
+	*/
+	Tenant *Tenant
+
 	mids       []Func
 	midCounter int
+	aborted    bool
+	abortCode  int // code of ctx.Abort(code), ctx.NotFound()...
+}
+
+/*
+Ctx is a context.Context, derived from the context of request: it's canceled when the client
+disconnects or when the Route.Timeout expires
+
+	func search(ctx *braza.Ctx) {
+		rows, err := db.QueryContext(ctx, "SELECT ...")
+		...
+	}
+*/
+func (ctx *Ctx) Deadline() (time.Time, bool) { return ctx.Request.Context().Deadline() }
+func (ctx *Ctx) Done() <-chan struct{}       { return ctx.Request.Context().Done() }
+func (ctx *Ctx) Err() error                  { return ctx.Request.Context().Err() }
+func (ctx *Ctx) Value(key any) any           { return ctx.Request.Context().Value(key) }
 
-	backCtx context.Context
+/*
+Set a value in the context of request (ctx.Value and ctx.Request.Context())
+
+	func auth(ctx *braza.Ctx) {
+		ctx.WithValue(userKey{}, user)
+		ctx.Next()
+	}
+	func index(ctx *braza.Ctx) {
+		user := ctx.Value(userKey{}).(*User)
+	}
+*/
+func (ctx *Ctx) WithValue(key, value any) {
+	rq := ctx.Request
+	rq.raw = rq.raw.WithContext(context.WithValue(rq.Context(), key, value))
 }
 
 func (ctx *Ctx) parseMids() {
@@ -145,22 +183,84 @@ func (ctx *Ctx) parseMids() {
 		ctx.MatchInfo.Router.Middlewares,
 		ctx.MatchInfo.Route.Middlewares,
 	)
-	ctx.mids = append(ctx.mids, ctx.MatchInfo.Func)
+	if f := ctx.MatchInfo.Func; f != nil {
+		// the body is read only if the request reaches the route func
+		ctx.mids = append(ctx.mids, func(ctx *Ctx) {
+			ctx.Request.parseBody()
+			ctx.Request.parseSchema()
+			f(ctx)
+		})
+	}
 }
 
-// executes the next middleware or main function of the request
+/*
+executes the next middleware or main function of the request and returns to the caller (onion model)
+
+	func logger(ctx *braza.Ctx) {
+		start := time.Now()
+		ctx.Next()
+		// the response is final here, but the headers are not sent
+		log.Println(ctx.StatusCode, ctx.Len(), time.Since(start))
+	}
+
+if a middleware doesn't call Next, the chain continues after it returns (unless ctx.Abort).
+the aborts (ctx.JSON, ctx.NotFound...) stop the chain and return to the previous middlewares
+*/
 func (ctx *Ctx) Next() {
+	ctx.midCounter++
+	for ctx.midCounter < len(ctx.mids) && !ctx.aborted {
+		if f := ctx.mids[ctx.midCounter]; f != nil {
+			ctx.execMid(f)
+		}
+		ctx.midCounter++
+	}
+}
+
+// exec a func of chain, recovering the aborts. the other panics unwind to the app (500)
+func (ctx *Ctx) execMid(f Func) {
+	defer func() {
+		if err := recover(); err != nil {
+			if e, ok := err.(error); !ok || !errors.Is(e, ErrHttpAbort) {
+				panic(err)
+			}
+			ctx.aborted = true
+			if code, ok := ctx.takeAbortCode(); ok {
+				ctx.App.execHandlerError(ctx, code) // the previous middlewares see the error response
+			}
+		}
+	}()
+	f(ctx)
+}
+
+// returns the code of ctx.Abort(code), ctx.NotFound()... and clean it
+func (ctx *Ctx) takeAbortCode() (int, bool) {
+	code := ctx.abortCode
+	ctx.abortCode = 0
+	return code, code != 0
+}
+
+/*
+Stop the chain of middlewares. With ctx.Abort() the current func continues, but the next funcs of chain are skipped.
+With a code, ctx.Abort(403) also stops the current func and responds with the error of code (like ctx.Forbidden())
 
-	if ctx.midCounter < len(ctx.mids) {
-		n := ctx.mids[ctx.midCounter]
-		ctx.midCounter += 1
-		n(ctx)
-		if ctx.midCounter < len(ctx.mids) {
-			ctx.Next()
+	func auth(ctx *braza.Ctx) {
+		if ctx.Request.Header.Get("Authorization") == "" {
+			ctx.Abort(401)
 		}
+		ctx.Next()
+	}
+*/
+func (ctx *Ctx) Abort(code ...int) {
+	ctx.aborted = true
+	if len(code) > 0 {
+		ctx.Response.Abort(code[0])
 	}
 }
 
+// if the chain was stopped (by ctx.Abort, ctx.JSON, ctx.NotFound...)
+func (ctx *Ctx) IsAborted() bool { return ctx.aborted }
+
+// Url Builder. if the request has a tenant, uses the UrlArgs and the Host of tenant
 func (ctx *Ctx) UrlFor(name string, external bool, args ...string) string {
-	return ctx.App.UrlFor(name, external, args...)
+	return ctx.App.urlFor(ctx.Tenant, name, external, args...)
 }
@@ -14,28 +14,39 @@ func Daemon(apps ...*App) error {
 	}
 	cErrs := make(chan map[string]error)
 	countErrors := map[string]int{}
-	if mapStackApps == nil {
-		mapStackApps = map[string]*App{}
+
+	if needSupervisor(apps[0]) {
+		// the supervisor only watches and restarts; the child process serves all apps
+		apps[0].Build()
+		return runSupervisor(apps[0])
 	}
 
 	for c, app := range apps {
 		if app.Name == "" {
 			l.warn.Println("When using 'Daemon', a good practice is to name all 'apps'")
 		}
-		countErrors[app.uuid] = 0
-		mapStackApps[app.uuid] = app
 		if c > 0 {
 			app.DisableFileWatcher = true
 		}
-		app.Build()
+		app.Build() // sets the uuid
+		countErrors[app.uuid] = 0
+		stackApp(app) // before the goroutines start: runSrv doesn't register it again
 		go runAppDaemon(app, cErrs)
 	}
 
-	for {
-		<-cErrs
-		for _, a := range mapStackApps {
-			a.Srv.Close()
+	// if a app stops, all stop. in a graceful reload, waits all apps be drained
+	var firstErr error
+	for range apps {
+		for _, e := range <-cErrs {
+			if firstErr == nil {
+				firstErr = e
+			}
+		}
+		for _, a := range stackApps() {
+			if !a.draining.Load() {
+				a.closeSrv()
+			}
 		}
 	}
-
+	return firstErr
 }
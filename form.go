@@ -0,0 +1,212 @@
+package braza
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+Decode the flat keys of query, form and multipart in nested values, like a json body:
+
+	"tags=a&tags=b"                       -> {"tags": ["a", "b"]}
+	"tags[]=a"                            -> {"tags": ["a"]}
+	"user.name=joe&user.age=20"           -> {"user": {"name": "joe", "age": "20"}}
+	"user[name]=joe"                      -> {"user": {"name": "joe"}}
+	"items[0].name=a&items[1].name=b"     -> {"items": [{"name": "a"}, {"name": "b"}]}
+	"items[0][name]=a&items[0][qty]=2"    -> {"items": [{"name": "a", "qty": "2"}]}
+
+the keys with only one value are strings, the repeated keys are []any
+*/
+func decodeValues(values url.Values) map[string]any {
+	root := map[string]any{}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys) // "a" before "a.b": the conflicts are resolved always in the same way
+	for _, k := range keys {
+		path := splitFormKey(k)
+		for _, v := range values[k] {
+			setFormValue(root, path, v)
+		}
+	}
+	return finishFormValue(root).(map[string]any)
+}
+
+// "items[0].name" -> ["items", "0", "name"]; "tags[]" -> ["tags", ""]
+func splitFormKey(key string) []string {
+	path := []string{}
+	seg := strings.Builder{}
+	inBracket := false
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c == '[' && !inBracket:
+			if seg.Len() > 0 || len(path) == 0 {
+				path = append(path, seg.String())
+			}
+			seg.Reset()
+			inBracket = true
+		case c == ']' && inBracket:
+			path = append(path, seg.String())
+			seg.Reset()
+			inBracket = false
+		case c == '.' && !inBracket:
+			if seg.Len() > 0 {
+				path = append(path, seg.String())
+			}
+			seg.Reset()
+		default:
+			seg.WriteByte(c)
+		}
+	}
+	if seg.Len() > 0 || len(path) == 0 {
+		path = append(path, seg.String())
+	}
+	return path
+}
+
+// the lists are maps of indexes ("0", "1"...) until finishFormValue
+func setFormValue(node map[string]any, path []string, value string) {
+	key := path[0]
+	if len(path) == 1 || (len(path) == 2 && path[1] == "") {
+		switch old := node[key].(type) {
+		case nil:
+			if len(path) == 2 { // "tags[]"
+				node[key] = []any{value}
+			} else {
+				node[key] = value
+			}
+		case string:
+			node[key] = []any{old, value}
+		case []any:
+			node[key] = append(old, value)
+		}
+		// a key with value and children ("user=1&user.name=joe"): the first wins
+		return
+	}
+	child, ok := node[key].(map[string]any)
+	if !ok {
+		if node[key] != nil {
+			return
+		}
+		child = map[string]any{}
+		node[key] = child
+	}
+	next := path[1:]
+	if next[0] == "" { // "items[].name": a new item for each value
+		next = append([]string{strconv.Itoa(len(child))}, next[1:]...)
+	}
+	setFormValue(child, next, value)
+}
+
+// convert the maps of indexes in lists
+func finishFormValue(v any) any {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return v
+	}
+	indexes := make([]int, 0, len(m))
+	for k, child := range m {
+		m[k] = finishFormValue(child)
+		if i, err := strconv.Atoi(k); err == nil && i >= 0 {
+			indexes = append(indexes, i)
+		}
+	}
+	if len(m) == 0 || len(indexes) != len(m) {
+		return m
+	}
+	sort.Ints(indexes)
+	list := make([]any, 0, len(indexes))
+	for _, i := range indexes {
+		list = append(list, m[strconv.Itoa(i)])
+	}
+	return list
+}
+
+/*
+Decode a xml document in nested values, like a json body. The children of root are the keys:
+
+	<user><name>joe</name><tags>a</tags><tags>b</tags></user> -> {"name": "joe", "tags": ["a", "b"]}
+
+the attributes are ignored
+*/
+func decodeXML(data []byte) (map[string]any, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := tok.(xml.StartElement); ok {
+			v, err := decodeXMLElement(dec)
+			if err != nil {
+				return nil, err
+			}
+			if m, ok := v.(map[string]any); ok {
+				return m, nil
+			}
+			return map[string]any{}, nil
+		}
+	}
+}
+
+// returns the text of element, or a map of children
+func decodeXMLElement(dec *xml.Decoder) (any, error) {
+	children := map[string]any{}
+	text := strings.Builder{}
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			v, err := decodeXMLElement(dec)
+			if err != nil {
+				return nil, err
+			}
+			switch old := children[t.Name.Local].(type) {
+			case nil:
+				children[t.Name.Local] = v
+			case []any:
+				children[t.Name.Local] = append(old, v)
+			default:
+				children[t.Name.Local] = []any{old, v}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if len(children) > 0 {
+				return children, nil
+			}
+			return strings.TrimSpace(text.String()), nil
+		}
+	}
+}
+
+// the yaml maps are map[any]any: convert in map[string]any, like a json body
+func normalizeYAML(v any) any {
+	switch t := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(t))
+		for k, v := range t {
+			m[fmt.Sprint(k)] = normalizeYAML(v)
+		}
+		return m
+	case map[string]any:
+		for k, v := range t {
+			t[k] = normalizeYAML(v)
+		}
+	case []any:
+		for i, v := range t {
+			t[i] = normalizeYAML(v)
+		}
+	}
+	return v
+}
@@ -0,0 +1,59 @@
+package braza
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeValues(t *testing.T) {
+	cases := []struct {
+		query string
+		want  map[string]any
+	}{
+		{"name=joe", map[string]any{"name": "joe"}},
+		{"tags=a&tags=b", map[string]any{"tags": []any{"a", "b"}}},
+		{"tags[]=a", map[string]any{"tags": []any{"a"}}},
+		{"user.name=joe&user.age=20", map[string]any{"user": map[string]any{"name": "joe", "age": "20"}}},
+		{"user[name]=joe", map[string]any{"user": map[string]any{"name": "joe"}}},
+		{"items[0].name=a&items[1].name=b", map[string]any{"items": []any{
+			map[string]any{"name": "a"},
+			map[string]any{"name": "b"},
+		}}},
+		{"items[0][name]=a&items[0][qty]=2", map[string]any{"items": []any{
+			map[string]any{"name": "a", "qty": "2"},
+		}}},
+	}
+	for _, c := range cases {
+		values, err := url.ParseQuery(c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := decodeValues(values); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s:\n got %#v\nwant %#v", c.query, got, c.want)
+		}
+	}
+}
+
+func TestNestedQueryAndForm(t *testing.T) {
+	var query, form map[string]any
+	app := newTestApp(t, nil, POST("/orders", func(ctx *Ctx) {
+		query = ctx.Request.QueryValues
+		form = ctx.Request.Form
+		ctx.NoContent()
+	}))
+	rec := doRequest(app, "POST", "/orders?filter[status]=open", "items[0].sku=x1&items[0].qty=2&tags=a&tags=b",
+		"Content-Type", "application/x-www-form-urlencoded")
+	expectStatus(t, rec, 204)
+
+	if want := map[string]any{"filter": map[string]any{"status": "open"}}; !reflect.DeepEqual(query, want) {
+		t.Errorf("query: %#v", query)
+	}
+	want := map[string]any{
+		"items": []any{map[string]any{"sku": "x1", "qty": "2"}},
+		"tags":  []any{"a", "b"},
+	}
+	if !reflect.DeepEqual(form, want) {
+		t.Errorf("form: %#v", form)
+	}
+}
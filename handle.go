@@ -0,0 +1,147 @@
+package braza
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+/*
+A typed handler: receives the schema of request already bound and validated,
+and returns the body of response or an error
+
+	type CreateUser struct {
+		Name  string `braza:"in=body,required,min=3"`
+		Email string `braza:"in=body,required,email"`
+	}
+
+	type User struct {
+		ID    int    `json:"id"`
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+
+	func createUser(ctx *braza.Ctx, in *CreateUser) (*User, error) {
+		if exists(in.Email) {
+			return nil, braza.NewProblem(409, "the email is already registered")
+		}
+		return db.CreateUser(in.Name, in.Email), nil
+	}
+
+	app.AddRoute(braza.Handle("POST", "/users", createUser))
+*/
+type HandlerFunc[In, Out any] func(ctx *Ctx, in *In) (Out, error)
+
+// errors with a http status code
+//
+//	type ErrConflict struct{}
+//	func (ErrConflict) Error() string   { return "conflict" }
+//	func (ErrConflict) StatusCode() int { return 409 }
+type StatusCoder interface {
+	StatusCode() int
+}
+
+/*
+Returns a route with a typed handler. The Schema and RespSchema of route are derived
+from In and Out. Use struct{} as In if the route doesn't have a schema
+
+	app.AddRoute(braza.Handle("GET", "/users/{id:int}", getUser))
+
+	func getUser(ctx *braza.Ctx, in *GetUser) (*User, error) {
+		user := db.FindUser(in.ID)
+		if user == nil {
+			return nil, braza.ErrorNotFound // 404
+		}
+		return user, nil // 200: json, xml, yaml... (see ctx.Render)
+	}
+
+the returned errors are mapped to status codes:
+  - *braza.Problem: the status of problem
+  - SchemaError: 400
+  - braza.ErrorNotFound: 404
+  - braza.ErrorMethodMismatch: 405
+  - an error with the method StatusCode() int: the status of error
+  - other errors: 500
+
+if Out has the method StatusCode() int, it is used as the status code of response (default 200)
+*/
+func Handle[In, Out any](method, url string, f HandlerFunc[In, Out]) *Route {
+	if f == nil {
+		panic("handler is nil")
+	}
+	rt := reflect.TypeOf((*In)(nil)).Elem()
+	if rt.Kind() != reflect.Struct {
+		panic(fmt.Errorf("the input of handler must be a struct, not '%s'", rt))
+	}
+	code := 200
+	if ot := reflect.TypeOf((*Out)(nil)).Elem(); ot.Kind() != reflect.Pointer && ot.Kind() != reflect.Interface {
+		if sc, ok := any(*new(Out)).(StatusCoder); ok {
+			code = sc.StatusCode()
+		}
+	}
+	r := &Route{
+		Url:        url,
+		Name:       getFunctionName(f),
+		Methods:    []string{strings.ToUpper(method)},
+		RespSchema: RespSchema{code: new(Out)},
+	}
+	hasInput := rt.NumField() > 0
+	if hasInput {
+		r.Schema = new(In)
+	}
+	r.Func = func(ctx *Ctx) {
+		in, ok := ctx.Schema.(*In)
+		if !ok {
+			if hasInput {
+				// a bug of binding: never run the handler with a empty input
+				l.err.Printf("route '%s': the schema is a '%T', not a '*%s'", ctx.MatchInfo.Route.Name, ctx.Schema, rt)
+				ctx.InternalServerError()
+			}
+			in = new(In)
+		}
+		out, err := f(ctx, in)
+		if err != nil {
+			ctx.handleError(err)
+		}
+		code := 200
+		if sc, ok := any(out).(StatusCoder); ok {
+			code = sc.StatusCode()
+		}
+		ctx.Render(out, code)
+	}
+	return r
+}
+
+// responds the error returned by a typed handler
+func (ctx *Ctx) handleError(err error) {
+	var (
+		p  *Problem
+		se SchemaError
+		sc StatusCoder
+	)
+	switch {
+	case errors.As(err, &p):
+		ctx.Problem(p)
+	case errors.As(err, &se):
+		if ctx.App.ProblemDetails {
+			ctx.Problem(schemaProblem(se))
+		}
+		ctx.JSON(se, 400)
+	case errors.Is(err, ErrorNotFound):
+		ctx.NotFound()
+	case errors.Is(err, ErrorMethodMismatch):
+		ctx.MethodNotAllowed()
+	case errors.As(err, &sc):
+		code := sc.StatusCode()
+		if code >= 500 {
+			l.err.Println(err)
+		}
+		if ctx.App.ProblemDetails {
+			ctx.Problem(NewProblem(code, err.Error()))
+		}
+		ctx.JSON(map[string]string{"error": err.Error()}, code)
+	}
+	l.err.Println(err)
+	ctx.InternalServerError()
+}
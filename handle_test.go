@@ -0,0 +1,65 @@
+package braza
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+type testCreateUser struct {
+	Name string `braza:"in=body,required"`
+}
+
+type testCreated struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func (testCreated) StatusCode() int { return 201 }
+
+type testConflict struct{}
+
+func (testConflict) Error() string   { return "conflict" }
+func (testConflict) StatusCode() int { return 409 }
+
+func TestHandleTyped(t *testing.T) {
+	route := Handle("POST", "/users", func(ctx *Ctx, in *testCreateUser) (testCreated, error) {
+		switch in.Name {
+		case "taken":
+			return testCreated{}, testConflict{}
+		case "missing":
+			return testCreated{}, ErrorNotFound
+		case "bug":
+			return testCreated{}, errors.New("db is down")
+		}
+		return testCreated{ID: 1, Name: in.Name}, nil
+	})
+	app := newTestApp(t, nil, route)
+	bind := ""
+	app.BeforeRequest = func(ctx *Ctx) {
+		if bind != "" {
+			ctx.Schema = &testCreateUser{Name: bind}
+		}
+	}
+	cases := map[string]int{"joe": 201, "taken": 409, "missing": 404, "bug": 500}
+	for name, code := range cases {
+		bind = name
+		rec := doRequest(app, "POST", "/users", `{}`, "Content-Type", "application/json", "Accept", "application/json")
+		expectStatus(t, rec, code)
+		if name == "joe" && rec.Body.String() != `{"id":1,"name":"joe"}` {
+			t.Errorf("body: %s", rec.Body.String())
+		}
+	}
+
+	// the schema was not bound: the handler must not run with a empty input
+	bind = ""
+	expectStatus(t, doRequest(app, "POST", "/users", `{}`, "Content-Type", "application/json"), http.StatusInternalServerError)
+}
+
+func TestHandleWithoutInput(t *testing.T) {
+	app := newTestApp(t, nil, Handle("GET", "/ping", func(ctx *Ctx, in *struct{}) (map[string]string, error) {
+		return map[string]string{"pong": "ok"}, nil
+	}))
+	rec := doRequest(app, "GET", "/ping", "", "Accept", "application/json")
+	expectStatus(t, rec, http.StatusOK)
+}
@@ -13,7 +13,7 @@ func serveFileHandler(ctx *Ctx) {
 	rq := ctx.Request
 
 	urlFilePath := rq.PathArgs["filepath"]
-	pathToFile := filepath.Join(ctx.App.StaticFolder, urlFilePath)
+	pathToFile := ctx.staticFile(urlFilePath)
 	if f, err := os.Open(pathToFile); err == nil {
 		_, file := filepath.Split(pathToFile)
 		defer f.Close()
@@ -47,6 +47,13 @@ func optionsHandler(ctx *Ctx) {
 	SetHeader(rsp.raw, rsp.header)
 }
 
+// exec the AfterRequest of app, before send the response
+func execAfterRequest(ctx *Ctx) {
+	if ctx.App.AfterRequest != nil && !ctx.Response.sent {
+		ctx.execMid(ctx.App.AfterRequest)
+	}
+}
+
 func execTeardown(ctx *Ctx) {
 	if ctx.App.TearDownRequest != nil {
 		go ctx.App.TearDownRequest(ctx)
@@ -66,13 +73,19 @@ func req500(ctx *Ctx) {
 func reqOK(ctx *Ctx) {
 	mi := ctx.MatchInfo
 	rsp := ctx.Response
+	if rsp.sent {
+		return
+	}
+	if ctx.App.liveReload != nil {
+		ctx.App.injectLiveReload(rsp)
+	}
 	if mi.Match {
 		if ctx.Session.changed {
 			rsp.SetCookie(ctx.Session.save(ctx))
 		}
 		rsp.parseHeaders()
-		SetHeader(rsp.raw, rsp.header)
 	}
+	SetHeader(rsp.raw, rsp.header)
 	rsp.raw.WriteHeader(rsp.StatusCode)
 	fmt.Fprint(rsp.raw, rsp.String())
 }
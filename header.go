@@ -28,15 +28,18 @@ import (
 
 // Set a Cookie. Has the same effect as 'Response.SetCookie'
 func SetCookie(h http.Header, cookie *http.Cookie) {
+	if cookie == nil {
+		return
+	}
 	if v := cookie.String(); v != "" {
 		h.Add("Set-Cookie", v)
 	}
 }
 
-// Write the headers in the response
+// Write the headers in the response (all the values, ex: many 'Set-Cookie')
 func SetHeader(w http.ResponseWriter, h http.Header) {
-	for key := range h {
-		w.Header().Set(key, h.Get(key))
+	for key, values := range h {
+		w.Header()[key] = values
 	}
 }
 
@@ -0,0 +1,60 @@
+package braza
+
+import (
+	"io"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// a built app with the routes, without the static endpoint
+func newTestApp(t *testing.T, cfg *Config, routes ...*Route) *App {
+	t.Helper()
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	cfg.DisableStatic = true
+	app := NewApp(cfg)
+	for _, r := range routes {
+		app.AddRoute(r)
+	}
+	app.Build()
+	return app
+}
+
+// exec a request in the app. headers: "Key", "value", ...
+func doRequest(app *App, method, url, body string, headers ...string) *httptest.ResponseRecorder {
+	var rd io.Reader
+	if body != "" {
+		rd = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, url, rd)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	return rec
+}
+
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, code int) {
+	t.Helper()
+	if rec.Code != code {
+		t.Fatalf("status: got %d, want %d (body: %s)", rec.Code, code, rec.Body.String())
+	}
+}
+
+// serve the app on the listener (the app must not be in dev mode: no supervisor), closed on cleanup
+func serveTestApp(t *testing.T, app *App, ln net.Listener) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		app.Serve(ln)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		app.Srv.Close() // runSrv closes the rest
+		<-done
+	})
+}
@@ -0,0 +1,151 @@
+package braza
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+/*
+host patterns of routers (Router.Subdomain, Router.Host and the servernames):
+
+	"example.com"           // literal host
+	"{tenant}.example.com"  // variable label (in ctx.Request.PathArgs["tenant"])
+	"{id:int}.example.com"  // variable label with converter
+	"*.example.com"         // any label
+	"**.example.com"        // one or more labels (a.example.com, a.b.example.com)
+	"{sub:**}.example.com"  // one or more labels, in a variable ("a.b")
+
+the port is ignored and the labels are case-insensitive
+*/
+type hostPattern struct {
+	raw   string
+	regex *regexp.Regexp
+	vars  []*urlSegment
+	rank  int // the less specific patterns are matched last
+}
+
+// regex of a label of host: 'str' does not match dots
+const hostLabel = `[a-zA-Z0-9\-_]+`
+
+func (app *App) compileHost(routerName, pattern string) *hostPattern {
+	h := &hostPattern{raw: strings.TrimSuffix(pattern, ".")}
+	buf := strings.Builder{}
+	for i, label := range strings.Split(h.raw, ".") {
+		if i > 0 {
+			buf.WriteString(`\.`)
+		}
+		switch {
+		case label == "":
+			panic(fmt.Errorf("Router '%s' has a invalid host: '%s'", routerName, pattern))
+		case label == "*":
+			h.rank += 10
+			buf.WriteString(hostLabel)
+		case label == "**":
+			h.rank += 100
+			buf.WriteString(hostLabel + `(?:\.` + hostLabel + `)*`)
+		case isUrlVar(label):
+			name, conv, _ := strings.Cut(label[1:len(label)-1], ":")
+			seg := &urlSegment{name: name, isVar: true}
+			if !reVarName.MatchString(name) || name == "*" {
+				panic(fmt.Errorf("Router '%s' has a invalid host variable: '%s'", routerName, label))
+			}
+			pat := hostLabel
+			switch conv {
+			case "", "str":
+				h.rank++
+			case "**":
+				h.rank += 100
+				pat = hostLabel + `(?:\.` + hostLabel + `)*`
+			default:
+				c, ok := app.getConverter(conv)
+				if !ok {
+					panic(fmt.Errorf("Router '%s' has a unknown converter in host: '%s'", routerName, label))
+				}
+				h.rank++
+				seg.conv = c
+				pat = c.Pattern
+			}
+			seg.regex = regexp.MustCompile("^(?i:" + pat + ")$")
+			buf.WriteString(fmt.Sprintf("(?P<v%d>%s)", len(h.vars), pat))
+			h.vars = append(h.vars, seg)
+		default:
+			h.rank-- // more literal labels, more specific
+			buf.WriteString(regexp.QuoteMeta(label))
+		}
+	}
+	h.regex = regexp.MustCompile("^(?i:" + buf.String() + ")$")
+	return h
+}
+
+// lowercase host, without port and the final dot
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// 'https://example.com:8000/' -> 'example.com', '8000'
+func normalizeServername(srv string) (string, string) {
+	srv = strings.TrimPrefix(srv, "https//")
+	srv = strings.TrimPrefix(srv, "https://")
+	srv = strings.TrimPrefix(srv, "http://")
+	srv = strings.TrimPrefix(strings.TrimSuffix(srv, "/"), ".")
+	if h, p, err := net.SplitHostPort(srv); err == nil {
+		return strings.ToLower(h), p
+	}
+	return strings.ToLower(srv), ""
+}
+
+// returns the values of host variables, if the host matches
+func (h *hostPattern) match(host string) (map[string]string, map[string]any, bool) {
+	m := h.regex.FindStringSubmatch(normalizeHost(host))
+	if m == nil {
+		return nil, nil, false
+	}
+	args := map[string]string{}
+	values := map[string]any{}
+	for i, seg := range h.vars {
+		str := m[h.regex.SubexpIndex(fmt.Sprintf("v%d", i))]
+		v, _, err := seg.parse(str)
+		if err != nil {
+			return nil, nil, false
+		}
+		args[seg.name] = str
+		values[seg.name] = v
+	}
+	return args, values, true
+}
+
+// build the host with the values of variables. the used params are removed
+func (h *hostPattern) build(params map[string]string) string {
+	labels := strings.Split(h.raw, ".")
+	for i, label := range labels {
+		if label == "*" || label == "**" {
+			panic(fmt.Errorf("the host '%s' has wildcards, it's not possible build a url to it", h.raw))
+		}
+		if !isUrlVar(label) {
+			continue
+		}
+		name, _, _ := strings.Cut(label[1:len(label)-1], ":")
+		value, ok := params[name]
+		if !ok {
+			panic(fmt.Errorf("the host '%s' needs parameter '%s' but not passed", h.raw, name))
+		}
+		for _, seg := range h.vars {
+			if seg.name != name {
+				continue
+			}
+			_, str, err := seg.parse(value)
+			if err != nil || !seg.regex.MatchString(value) {
+				panic(fmt.Errorf("the host '%s': invalid value of parameter '%s': '%s'", h.raw, name, value))
+			}
+			value = str
+		}
+		labels[i] = value
+		delete(params, name)
+	}
+	return strings.Join(labels, ".")
+}
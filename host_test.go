@@ -0,0 +1,57 @@
+package braza
+
+import (
+	"testing"
+)
+
+func TestHostRouting(t *testing.T) {
+	text := func(s string) Func {
+		return func(ctx *Ctx) { ctx.TEXT(s+ctx.Request.PathArgs["shop"]+ctx.Request.PathArgs["sub"], 200) }
+	}
+
+	api := NewRouter("api")
+	api.Subdomain = "api"
+	api.GET("/", text("api"))
+
+	shops := NewRouter("shops")
+	shops.Subdomain = "{shop}"
+	shops.Add("/", "index", text("shop:"), []string{"GET"})
+
+	ids := NewRouter("ids")
+	ids.Subdomain = "{id:int}.users"
+	ids.GET("/", text("user"))
+
+	custom := NewRouter("custom")
+	custom.Host = "{sub:**}.custom.org"
+	custom.GET("/", text("custom:"))
+
+	app := NewApp(&Config{DisableStatic: true, Servername: "example.com:443", ListeningInTLS: true})
+	app.GET("/", text("main"))
+	app.Mount(api, shops, ids, custom)
+	app.Build()
+
+	cases := []struct {
+		url  string
+		code int
+		body string
+	}{
+		{"http://example.com/", 200, "main"},
+		{"http://EXAMPLE.com:5000/", 200, "main"},
+		{"http://api.example.com/", 200, "api"}, // the literal subdomain before the variable
+		{"http://acme.example.com/", 200, "shop:acme"},
+		{"http://42.users.example.com/", 200, "user"},
+		{"http://x.users.example.com/", 404, ""},
+		{"http://a.b.custom.org/", 200, "custom:a.b"},
+		{"http://other.org/", 404, ""},
+	}
+	for _, c := range cases {
+		rec := doRequest(app, "GET", c.url, "")
+		if rec.Code != c.code || (c.code == 200 && rec.Body.String() != c.body) {
+			t.Errorf("%s: got %d %q, want %d %q", c.url, rec.Code, rec.Body.String(), c.code, c.body)
+		}
+	}
+
+	if url := app.UrlFor("shops.index", true, "shop", "acme"); url != "https://acme.example.com/" {
+		t.Errorf("UrlFor of host variable: %s", url)
+	}
+}
@@ -0,0 +1,187 @@
+package braza
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	listenFdsStart = 3 // first file descriptor passed by systemd (SD_LISTEN_FDS_START)
+
+	envListenFds     = "BRAZA_LISTEN_FDS"
+	envListenFdNames = "BRAZA_LISTEN_FDNAMES"
+	envReadyFd       = "BRAZA_READY_FD"
+)
+
+type inheritedListener struct {
+	name string
+	ln   net.Listener
+	used bool
+}
+
+var (
+	inheritedOnce      sync.Once
+	inheritedMu        sync.Mutex
+	inheritedListeners []*inheritedListener
+	readyFd            = -1
+)
+
+/*
+load the sockets passed by systemd (socket activation)
+
+	LISTEN_PID=<pid of this process>
+	LISTEN_FDS=<number of sockets, starting from fd 3>
+	LISTEN_FDNAMES=<name1:name2:...> // optional
+
+or by the old process of a graceful reload (BRAZA_LISTEN_FDS, BRAZA_LISTEN_FDNAMES and BRAZA_READY_FD)
+*/
+func loadInheritedListeners() {
+	var nfds int
+	var names []string
+
+	if n, err := strconv.Atoi(os.Getenv(envListenFds)); err == nil {
+		nfds = n
+		names = strings.Split(os.Getenv(envListenFdNames), ":")
+		for i, n := range names {
+			names[i] = unescapeFdName(n)
+		}
+		if fd, err := strconv.Atoi(os.Getenv(envReadyFd)); err == nil {
+			readyFd = fd
+		}
+	} else if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err == nil && pid == os.Getpid() {
+		nfds, _ = strconv.Atoi(os.Getenv("LISTEN_FDS"))
+		names = strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	}
+
+	// the child processes must not inherit this vars
+	for _, k := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES", envListenFds, envListenFdNames, envReadyFd} {
+		os.Unsetenv(k)
+	}
+
+	for i := 0; i < nfds; i++ {
+		fd := listenFdsStart + i
+		name := ""
+		if i < len(names) {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(fd), name)
+		ln, err := net.FileListener(f) // dup the fd with close-on-exec
+		f.Close()
+		if err != nil {
+			l.warn.Printf("inherited fd %d is not a listener: %v", fd, err)
+			continue
+		}
+		inheritedListeners = append(inheritedListeners, &inheritedListener{name: name, ln: ln})
+	}
+}
+
+// the names of BRAZA_LISTEN_FDNAMES are separated by ':', so the app names are escaped
+func escapeFdName(name string) string { return url.QueryEscape(name) }
+
+func unescapeFdName(name string) string {
+	if n, err := url.QueryUnescape(name); err == nil {
+		return n
+	}
+	return name
+}
+
+// tells the old process of a graceful reload that all inherited listeners are being served
+func notifyReady() {
+	inheritedMu.Lock()
+	defer inheritedMu.Unlock()
+	if readyFd < 0 {
+		return
+	}
+	for _, il := range inheritedListeners {
+		if !il.used {
+			return
+		}
+	}
+	f := os.NewFile(uintptr(readyFd), "ready")
+	f.Write([]byte{1})
+	f.Close()
+	readyFd = -1
+}
+
+// returns a inherited listener for this app: by app name, or a unnamed one on App.Srv.Addr.
+// nil if none matches (the app opens a new listener)
+func takeInheritedListener(app *App) net.Listener {
+	inheritedOnce.Do(loadInheritedListeners)
+	inheritedMu.Lock()
+	defer inheritedMu.Unlock()
+
+	if app.Name != "" {
+		for _, il := range inheritedListeners {
+			if !il.used && il.name == app.Name {
+				il.used = true
+				return il.ln
+			}
+		}
+	}
+	for _, il := range inheritedListeners {
+		if !il.used && il.name == "" && sameAddr(il.ln, app.Srv.Addr) {
+			il.used = true
+			return il.ln
+		}
+	}
+	return nil
+}
+
+// if 'ln' is listening on 'addr' (ex: "[::]:5000" and ":5000")
+func sameAddr(ln net.Listener, addr string) bool {
+	lnAddr, ok := ln.Addr().(*net.TCPAddr)
+	if !ok {
+		return ln.Addr().String() == addr
+	}
+	tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil || tcpAddr.Port != lnAddr.Port {
+		return false
+	}
+	return tcpAddr.IP == nil || tcpAddr.IP.IsUnspecified() || tcpAddr.IP.Equal(lnAddr.IP)
+}
+
+// returns the listener of app: App.Serve -> systemd socket -> tcp on App.Srv.Addr
+func (app *App) listen() (net.Listener, error) {
+	if app.listener != nil {
+		return app.listener, nil
+	}
+	if ln := takeInheritedListener(app); ln != nil {
+		app.listener = ln
+		app.inherited = true
+		return ln, nil
+	}
+	ln, err := net.Listen("tcp", app.Srv.Addr)
+	if err != nil {
+		return nil, err
+	}
+	app.listener = ln
+	return ln, nil
+}
+
+// creates a unix socket listener, removing a stale socket file if exists
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if fi, err := os.Stat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("'%s' already exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+	return ln, nil
+}
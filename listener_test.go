@@ -0,0 +1,91 @@
+package braza
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTakeInheritedListener(t *testing.T) {
+	inheritedOnce.Do(func() {}) // don't load the fds of test process
+	listen := func() net.Listener {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { ln.Close() })
+		return ln
+	}
+	named, unnamed := listen(), listen()
+	inheritedMu.Lock()
+	inheritedListeners = []*inheritedListener{{name: "api", ln: named}, {ln: unnamed}}
+	inheritedMu.Unlock()
+	t.Cleanup(func() { inheritedListeners = nil })
+
+	other := &App{Router: &Router{}, Srv: &http.Server{Addr: "127.0.0.1:1"}}
+	if ln := takeInheritedListener(other); ln != nil {
+		t.Fatalf("a app on other addr got the listener %s", ln.Addr())
+	}
+	api := &App{Router: &Router{Name: "api"}, Srv: &http.Server{Addr: "127.0.0.1:1"}}
+	if ln := takeInheritedListener(api); ln != named {
+		t.Fatalf("the listener by name was not used: %v", ln)
+	}
+	byAddr := &App{Router: &Router{}, Srv: &http.Server{Addr: unnamed.Addr().String()}}
+	if ln := takeInheritedListener(byAddr); ln != unnamed {
+		t.Fatalf("the listener by addr was not used: %v", ln)
+	}
+}
+
+func TestListenUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false) // a socket file left by a crash
+	stale.Close()
+
+	ln, err := listenUnix(path, 0o600)
+	if err != nil {
+		t.Fatalf("the stale socket was not removed: %v", err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("mode of socket: %v, %v", fi.Mode(), err)
+	}
+
+	app := newTestApp(t, &Config{Env: "production", Silent: true, DisableGracefulReload: true},
+		GET("/", func(ctx *Ctx) { ctx.TEXT("unix", 200) }))
+	serveTestApp(t, app, ln)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return net.Dial("unix", path)
+		},
+	}}
+	resp, err := client.Get("http://unix/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "unix" {
+		t.Errorf("body: %q", body)
+	}
+}
+
+func TestListenUnixRefusesRegularFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+	if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listenUnix(path, 0); err == nil {
+		t.Fatal("a regular file was replaced by the socket")
+	}
+	if b, _ := os.ReadFile(path); string(b) != "data" {
+		t.Error("the file was removed")
+	}
+}
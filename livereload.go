@@ -0,0 +1,163 @@
+package braza
+
+import (
+	"bytes"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	liveReloadName = "_livereload"
+	liveReloadUrl  = "/_braza/livereload"
+)
+
+// reconnects after a server restart and reloads the page on changes
+const liveReloadScript = `<script>(function(){
+	var failed = false;
+	var es = new EventSource("%s");
+	es.onmessage = function(e){ if (e.data === "reload") location.reload(); };
+	es.onerror = function(){ failed = true; };
+	es.onopen = function(){ if (failed) location.reload(); };
+})();</script>`
+
+// notifies the browsers (sse clients) when the templates or static files change
+type liveReloader struct {
+	mu      sync.Mutex
+	clients map[chan string]bool
+}
+
+func newLiveReloader() *liveReloader {
+	return &liveReloader{clients: map[chan string]bool{}}
+}
+
+func (lr *liveReloader) subscribe() chan string {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	c := make(chan string, 1)
+	lr.clients[c] = true
+	return c
+}
+
+func (lr *liveReloader) unsubscribe(c chan string) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	delete(lr.clients, c)
+}
+
+func (lr *liveReloader) broadcast(msg string) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	for c := range lr.clients {
+		select {
+		case c <- msg:
+		default: // the client already has a pending reload
+		}
+	}
+}
+
+// watch the template and static folders (and the new sub directories)
+func (lr *liveReloader) watch(app *App) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		l.err.Println("livereload:", err)
+		return
+	}
+	defer fsw.Close()
+
+	addDir := func(dir string) {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && d.IsDir() {
+				fsw.Add(path)
+			}
+			return nil
+		})
+	}
+	addDir(app.TemplateFolder)
+	if !app.DisableStatic {
+		addDir(app.StaticFolder)
+	}
+
+	timer := time.NewTimer(0)
+	<-timer.C
+	for {
+		select {
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return
+			}
+			l.err.Println("livereload:", err)
+		case ev, ok := <-fsw.Events:
+			if !ok {
+				return
+			}
+			if ev.Has(fsnotify.Create) {
+				if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() {
+					addDir(ev.Name)
+				}
+			}
+			if !ev.Has(fsnotify.Chmod) {
+				timer.Reset(app.WatcherDebounce)
+			}
+		case <-timer.C:
+			lr.broadcast("reload")
+		}
+	}
+}
+
+// Server-Sent Events endpoint, keeps the connection open until the client leaves
+func liveReloadHandler(ctx *Ctx) {
+	lr := ctx.App.liveReload
+	rsp := ctx.Response
+	flusher, ok := rsp.raw.(http.Flusher)
+	if lr == nil || !ok {
+		ctx.NotFound()
+	}
+
+	h := rsp.raw.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	rsp.raw.WriteHeader(200)
+	rsp.raw.Write([]byte(": connected\n\n"))
+	flusher.Flush()
+	rsp.sent = true
+
+	c := lr.subscribe()
+	defer lr.unsubscribe(c)
+	done := ctx.Request.Context().Done()
+	for {
+		select {
+		case <-done:
+			ctx.Close()
+		case msg := <-c:
+			rsp.raw.Write([]byte("data: " + msg + "\n\n"))
+			flusher.Flush()
+		}
+	}
+}
+
+// insert the live reload script into html responses
+func (app *App) injectLiveReload(rsp *Response) {
+	ct := rsp.header.Get("Content-Type")
+	if ct == "" {
+		ct = http.DetectContentType(rsp.Bytes())
+	}
+	if !strings.HasPrefix(ct, "text/html") {
+		return
+	}
+	script := []byte(strings.Replace(liveReloadScript, "%s", app.UrlFor(liveReloadName, false), 1))
+	body := rsp.Bytes()
+	if i := bytes.LastIndex(body, []byte("</body>")); i >= 0 {
+		body = bytes.Join([][]byte{body[:i], script, body[i:]}, nil)
+	} else {
+		body = bytes.Join([][]byte{body, script}, nil)
+	}
+	rsp.Reset()
+	rsp.Write(body)
+}
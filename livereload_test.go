@@ -0,0 +1,67 @@
+package braza
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLiveReloadInjectsScript(t *testing.T) {
+	page := GET("/", func(ctx *Ctx) { ctx.HTML("<html><body><h1>hi</h1></body></html>", 200) })
+	api := GET("/api", func(ctx *Ctx) { ctx.JSON(map[string]string{"body": "</body>"}, 200) })
+	api.Name = "api"
+	app := newTestApp(t, &Config{LiveReload: true}, page, api)
+
+	body := doRequest(app, "GET", "/", "").Body.String()
+	if !strings.Contains(body, "EventSource(\""+liveReloadUrl+"\")") || !strings.HasSuffix(body, "</script></body></html>") {
+		t.Errorf("the script was not injected before </body>: %s", body)
+	}
+	if body := doRequest(app, "GET", "/api", "").Body.String(); strings.Contains(body, "<script>") {
+		t.Errorf("the script was injected in a json response: %s", body)
+	}
+
+	app = newTestApp(t, &Config{LiveReload: true, Env: "production"}, page)
+	if body := doRequest(app, "GET", "/", "").Body.String(); strings.Contains(body, "<script>") {
+		t.Error("the live reload must only run in development")
+	}
+	expectStatus(t, doRequest(app, "GET", liveReloadUrl, ""), 404)
+}
+
+func TestLiveReloadEvents(t *testing.T) {
+	app := newTestApp(t, &Config{LiveReload: true})
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + liveReloadUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type: %s", ct)
+	}
+	rd := bufio.NewReader(resp.Body)
+	if line, err := rd.ReadString('\n'); err != nil || line != ": connected\n" {
+		t.Fatalf("first line: %q, %v", line, err)
+	}
+	rd.ReadString('\n')
+
+	// the client is subscribed after the first flush
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		app.liveReload.mu.Lock()
+		n := len(app.liveReload.clients)
+		app.liveReload.mu.Unlock()
+		if n == 1 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	app.liveReload.broadcast("reload")
+	if line, err := rd.ReadString('\n'); err != nil || line != "data: reload\n" {
+		t.Fatalf("event: %q, %v", line, err)
+	}
+}
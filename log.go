@@ -116,7 +116,9 @@ func (l *logger) LogRequest(ctx *Ctx) {
 	if ctx.MatchInfo.Router != nil {
 		addr = ctx.MatchInfo.Router.Subdomain
 	}
-	if addr != "" {
+	if ctx.MatchInfo.Router != nil && ctx.MatchInfo.Router.Host != "" {
+		addr = rq.Host + rq.URL.Path
+	} else if addr != "" {
 		addr = addr + ".[...]" + rq.URL.Path
 	} else {
 		addr = rq.URL.Path
@@ -161,11 +163,11 @@ func (app *App) ShowRoutes() {
 		if pl := len(r.Url); pl > pathLen {
 			pathLen = pl
 		}
-		if r.router.Subdomain != "" {
+		if r.router.hostName() != "" {
 			router := r.router
 			// if router != nil && router.Subdomain != "" {
 			// }
-			if l := len(router.Subdomain); l > subDoLen {
+			if l := len(router.hostName()); l > subDoLen {
 				subDoLen = l
 			}
 		}
@@ -206,12 +208,12 @@ func (app *App) ShowRoutes() {
 			space1 := nameLen - len(rName)
 			space2 := methLen - len(mths_)
 			space3 := pathLen - len(r.Url)
-			space4 := subDoLen - len(r.GetRouter().Subdomain)
+			space4 := subDoLen - len(r.GetRouter().hostName())
 
 			endpoint := r.Name + strings.Repeat(" ", space1)
 			mths := mths_ + strings.Repeat(" ", space2)
 			path := r.Url + strings.Repeat(" ", space3)
-			sub := r.GetRouter().Subdomain + strings.Repeat(" ", space4)
+			sub := r.GetRouter().hostName() + strings.Repeat(" ", space4)
 			fmt.Printf("| %s | %s | %s | %s |\n", endpoint, mths, path, sub)
 		}
 		fmt.Printf("+-%s+-%s+-%s+-%s+\n", line1, line2, line3, line4)
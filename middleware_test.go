@@ -0,0 +1,134 @@
+package braza
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareOnion(t *testing.T) {
+	var trace []string
+	mid := func(name string) Func {
+		return func(ctx *Ctx) {
+			trace = append(trace, name+":before")
+			ctx.Next()
+			trace = append(trace, name+":after")
+		}
+	}
+	noNext := func(ctx *Ctx) { trace = append(trace, "noNext") }
+
+	route := &Route{
+		Url:         "/",
+		Name:        "index",
+		Middlewares: []Func{mid("route"), noNext},
+		Func: func(ctx *Ctx) {
+			trace = append(trace, "func")
+			ctx.TEXT("ok", 200)
+		},
+	}
+	app := NewApp(&Config{DisableStatic: true})
+	app.Middlewares = []Func{mid("app")}
+	app.AfterRequest = func(ctx *Ctx) { trace = append(trace, "afterRequest") }
+	app.AddRoute(route)
+	app.Build()
+
+	expectStatus(t, doRequest(app, "GET", "/", ""), 200)
+	want := "app:before,route:before,noNext,func,route:after,app:after,afterRequest"
+	if got := strings.Join(trace, ","); got != want {
+		t.Errorf("order:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestMiddlewareAbort(t *testing.T) {
+	var seen int
+	auth := func(ctx *Ctx) {
+		if ctx.Request.Header.Get("Authorization") == "" {
+			ctx.Abort(401)
+		}
+		ctx.Next()
+	}
+	logger := func(ctx *Ctx) {
+		ctx.Next()
+		seen = ctx.StatusCode // the final response, before it is sent
+	}
+	called := false
+	route := &Route{
+		Url:         "/",
+		Name:        "index",
+		Middlewares: []Func{logger, auth},
+		Func: func(ctx *Ctx) {
+			called = true
+			ctx.TEXT("secret", 200)
+		},
+	}
+	app := newTestApp(t, nil, route)
+	app.ErrorHandler(401, func(ctx *Ctx) { ctx.TEXT("login first", 401) })
+
+	rec := doRequest(app, "GET", "/", "")
+	expectStatus(t, rec, 401)
+	if called {
+		t.Error("the route func was called after the abort")
+	}
+	if seen != 401 || rec.Body.String() != "login first" {
+		t.Errorf("the previous middleware must see the error response: %d %q", seen, rec.Body.String())
+	}
+
+	rec = doRequest(app, "GET", "/", "", "Authorization", "token")
+	expectStatus(t, rec, 200)
+	if seen != 200 || !called {
+		t.Errorf("status seen by the middleware: %d", seen)
+	}
+}
+
+func TestWrapMiddleware(t *testing.T) {
+	type key struct{}
+	// sets a header and a value in the context of request
+	withValue := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Wrapped", "1")
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), key{}, "v")))
+		})
+	}
+	// wraps the writer: uppercases the body
+	upper := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := httptest.NewRecorder()
+			next.ServeHTTP(rec, r)
+			w.WriteHeader(rec.Code)
+			w.Write([]byte(strings.ToUpper(rec.Body.String())))
+		})
+	}
+	// doesn't call the next handler
+	deny := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Deny") != "" {
+				http.Error(w, "denied", 403)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	called := false
+	app := NewApp(&Config{DisableStatic: true})
+	app.Middlewares = []Func{WrapMiddleware(withValue), WrapMiddleware(deny), WrapMiddleware(upper)}
+	app.GET("/", func(ctx *Ctx) {
+		called = true
+		ctx.TEXT(ctx.Request.Context().Value(key{}), 201)
+	})
+	app.Build()
+
+	rec := doRequest(app, "GET", "/", "")
+	expectStatus(t, rec, 201)
+	if rec.Body.String() != "V" || rec.Header().Get("X-Wrapped") != "1" {
+		t.Errorf("the middlewares were not applied: %q %v", rec.Body.String(), rec.Header())
+	}
+
+	called = false
+	rec = doRequest(app, "GET", "/", "", "X-Deny", "1")
+	expectStatus(t, rec, 403)
+	if called || !strings.Contains(rec.Body.String(), "denied") {
+		t.Errorf("the chain must stop: called %v, body %q", called, rec.Body.String())
+	}
+}
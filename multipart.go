@@ -0,0 +1,273 @@
+package braza
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrBodyTooLarge  = errors.New("413 Request Entity Too Large")
+	errBodyWasRead   = errors.New("braza: the body of request was already read")
+	defaultMaxMemory = int64(10 << 20)
+)
+
+// Returns a file read in memory. The uploads of requests are parsed with the limits of Config
+func NewFile(p *multipart.Part) *File {
+	f, err := newFile(p, math.MaxInt64-1, 0)
+	if err != nil {
+		l.err.Panicln(err)
+	}
+	return f
+}
+
+/*
+A uploaded file. The small files are kept in memory (Stream), the files larger
+than Config.MultipartMemory are written in a temp file, removed after the response.
+
+Stream is nil for the files in disk: use File.Open or File.SaveTo, that work in both cases
+
+	func upload(ctx *braza.Ctx) {
+		file := ctx.Request.Files["avatar"][0]
+		if err := file.SaveTo("uploads/" + file.Filename); err != nil {
+			ctx.InternalServerError()
+		}
+		...
+	}
+*/
+type File struct {
+	Filename     string
+	ContentType  string
+	ContentLeght int
+	Size         int64
+	Header       textproto.MIMEHeader
+
+	// the content of file, if it is in memory (nil if the file is in disk. use File.Open)
+	Stream *bytes.Buffer
+
+	tmpFile string
+}
+
+// read the part in memory up to 'maxMemory' bytes, and the rest in a temp file. maxSize <= 0 is unlimited
+func newFile(p *multipart.Part, maxMemory, maxSize int64) (*File, error) {
+	f := &File{
+		Filename:    p.FileName(),
+		ContentType: p.Header.Get("Content-Type"),
+		Header:      p.Header,
+	}
+	limit := func(n int64) int64 {
+		if maxSize > 0 && maxSize < n {
+			return maxSize
+		}
+		return n
+	}
+
+	buf := &bytes.Buffer{}
+	n, err := io.CopyN(buf, p, limit(maxMemory)+1)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if maxSize > 0 && n > maxSize {
+		return nil, ErrBodyTooLarge
+	}
+	if n <= maxMemory {
+		f.Stream = buf
+		f.Size = n
+		f.ContentLeght = int(n)
+		return f, nil
+	}
+
+	tmp, err := os.CreateTemp("", "braza-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer tmp.Close()
+	f.tmpFile = tmp.Name()
+
+	var src io.Reader = io.MultiReader(buf, p)
+	if maxSize > 0 {
+		src = io.LimitReader(src, maxSize+1)
+	}
+	n, err = io.Copy(tmp, src)
+	if err == nil && maxSize > 0 && n > maxSize {
+		err = ErrBodyTooLarge
+	}
+	if err != nil {
+		f.Remove()
+		return nil, err
+	}
+	f.Size = n
+	f.ContentLeght = int(n)
+	return f, nil
+}
+
+// Returns a reader of content of file (in memory or in disk)
+func (f *File) Open() (io.ReadSeekCloser, error) {
+	if f.tmpFile != "" {
+		return os.Open(f.tmpFile)
+	}
+	if f.Stream == nil {
+		return nil, os.ErrNotExist
+	}
+	return nopSeekCloser{bytes.NewReader(f.Stream.Bytes())}, nil
+}
+
+// Save the file in the path (the dirs are created)
+func (f *File) SaveTo(path string) error {
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	dst, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// remove the temp file (if exists)
+func (f *File) Remove() error {
+	if f.tmpFile == "" {
+		return nil
+	}
+	err := os.Remove(f.tmpFile)
+	f.tmpFile = ""
+	return err
+}
+
+type nopSeekCloser struct{ *bytes.Reader }
+
+func (nopSeekCloser) Close() error { return nil }
+
+/*
+Returns a reader of multipart body, to process the parts on the fly (without memory or disk).
+The body can only be read once: disable the parse of form (Config.DisableParseFormBody)
+and the route must not have a Schema, or use the reader in a middleware (before the route func)
+
+	func upload(ctx *braza.Ctx) {
+		mr, err := ctx.Request.MultipartReader()
+		if err != nil {
+			ctx.BadRequest()
+		}
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			ctx.CheckErr(err)
+			s3.Upload(part.FileName(), part)
+		}
+		ctx.Created()
+	}
+*/
+func (r *Request) MultipartReader() (*multipart.Reader, error) {
+	if !strings.HasPrefix(r.ContentType, "multipart/") {
+		return nil, http.ErrNotMultipart
+	}
+	if r.bodyWasRead {
+		return nil, errBodyWasRead
+	}
+	boundary := r.Mime["boundary"]
+	if boundary == "" {
+		return nil, http.ErrMissingBoundary
+	}
+	r.limitBody()
+	r.bodyWasRead = true
+	return multipart.NewReader(r.raw.Body, boundary), nil
+}
+
+// read the parts of multipart body, with the limits of Config
+func (r *Request) parseMultipart() {
+	ctx := r.ctx
+	var mp *multipart.Reader
+	var err error
+	if r.bodyWasRead && r.Mime["boundary"] != "" {
+		mp = multipart.NewReader(bytes.NewReader(r.Body.Bytes()), r.Mime["boundary"]) // by Request.ReadBody
+	} else {
+		mp, err = r.MultipartReader()
+	}
+	if err != nil {
+		l.Error(err)
+		ctx.Response.BadRequest()
+	}
+	app := ctx.App
+	maxMemory := app.MultipartMemory
+	if maxMemory <= 0 {
+		maxMemory = defaultMaxMemory
+	}
+
+	fields := url.Values{}
+	b := &bytes.Buffer{}
+	for parts := 1; ; parts++ {
+		p, err := mp.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			r.bodyError(err)
+		}
+		if app.MaxParts > 0 && parts > app.MaxParts {
+			r.bodyError(ErrBodyTooLarge)
+		}
+		if p.FileName() != "" {
+			file, err := newFile(p, maxMemory, app.MaxFileSize)
+			if err != nil {
+				r.bodyError(err)
+			}
+			r.Files[p.FormName()] = append(r.Files[p.FormName()], file)
+		} else if p.FormName() != "" {
+			var src io.Reader = p
+			if app.MaxPartSize > 0 {
+				src = io.LimitReader(p, app.MaxPartSize+1)
+			}
+			n, err := b.ReadFrom(src)
+			if err != nil {
+				r.bodyError(err)
+			}
+			if app.MaxPartSize > 0 && n > app.MaxPartSize {
+				r.bodyError(ErrBodyTooLarge)
+			}
+			fields.Add(p.FormName(), b.String())
+			b.Reset()
+		}
+	}
+	for k, v := range decodeValues(fields) {
+		r.Form[k] = v
+	}
+}
+
+// abort the request with 413 if the body is too large, else 400
+func (r *Request) bodyError(err error) {
+	var maxErr *http.MaxBytesError
+	if errors.Is(err, ErrBodyTooLarge) || errors.As(err, &maxErr) {
+		r.ctx.Response.Abort(http.StatusRequestEntityTooLarge)
+	}
+	l.Error(err)
+	r.ctx.Response.BadRequest()
+}
+
+// remove the temp files of uploads, after the response
+func (r *Request) removeTempFiles() {
+	for _, files := range r.Files {
+		for _, f := range files {
+			f.Remove()
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package braza
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func multipartBody(files map[string]int, fields int) (*bytes.Buffer, string) {
+	b := &bytes.Buffer{}
+	w := multipart.NewWriter(b)
+	for name, size := range files {
+		fw, _ := w.CreateFormFile(name, name+".txt")
+		fw.Write([]byte(strings.Repeat("x", size)))
+	}
+	for i := 0; i < fields; i++ {
+		w.WriteField(fmt.Sprint("f", i), "v")
+	}
+	w.Close()
+	return b, w.FormDataContentType()
+}
+
+func uploadRequest(app *App, files map[string]int, fields int) *httptest.ResponseRecorder {
+	body, ct := multipartBody(files, fields)
+	req := httptest.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", ct)
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMultipartSpooling(t *testing.T) {
+	var tmp string
+	app := newTestApp(t, &Config{MultipartMemory: 1 << 10, MaxBodySize: 1 << 10}, POST("/upload", func(ctx *Ctx) {
+		small, big := ctx.Request.Files["small"][0], ctx.Request.Files["big"][0]
+		if small.Stream == nil || small.Size != 100 {
+			t.Errorf("small file: must be in memory")
+		}
+		if big.Stream != nil || big.tmpFile == "" || big.Size != 40<<10 {
+			t.Errorf("big file: must be in disk")
+		}
+		tmp = big.tmpFile
+		f, err := big.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		n, _ := io.Copy(io.Discard, f)
+		ctx.TEXT(fmt.Sprint(n), 200)
+	}))
+	// the multipart bodies are limited by MaxUploadSize, not MaxBodySize
+	rec := uploadRequest(app, map[string]int{"small": 100, "big": 40 << 10}, 0)
+	expectStatus(t, rec, http.StatusOK)
+	if rec.Body.String() != fmt.Sprint(40<<10) {
+		t.Errorf("read from disk: %s", rec.Body.String())
+	}
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Errorf("the temp file was not removed: %s", tmp)
+	}
+}
+
+func TestMultipartLimits(t *testing.T) {
+	app := newTestApp(t, &Config{MaxFileSize: 1 << 10, MaxParts: 10, MaxUploadSize: 64 << 10}, POST("/upload", func(ctx *Ctx) {
+		ctx.TEXT("ok", 200)
+	}))
+	expectStatus(t, uploadRequest(app, map[string]int{"a": 1 << 10}, 9), http.StatusOK)
+	expectStatus(t, uploadRequest(app, map[string]int{"a": 2 << 10}, 0), http.StatusRequestEntityTooLarge)
+	expectStatus(t, uploadRequest(app, nil, 11), http.StatusRequestEntityTooLarge)
+	expectStatus(t, uploadRequest(app, map[string]int{"a": 1 << 10, "b": 1 << 10}, 0), http.StatusOK)
+
+	app = newTestApp(t, &Config{MaxUploadSize: 8 << 10}, POST("/upload", func(ctx *Ctx) { ctx.TEXT("ok", 200) }))
+	expectStatus(t, uploadRequest(app, map[string]int{"a": 16 << 10}, 0), http.StatusRequestEntityTooLarge)
+}
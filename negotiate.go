@@ -0,0 +1,207 @@
+package braza
+
+import (
+	"mime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// a value of headers like 'Accept': "text/html;q=0.8"
+type qValue struct {
+	value string
+	q     float64
+}
+
+// parse the values and the quality of header, sorted by quality (the order of header on ties)
+//
+//	"text/html, application/json;q=0.9, */*;q=0.1"
+func parseQValues(header string) []qValue {
+	values := []qValue{}
+	for _, part := range strings.Split(header, ",") {
+		value, params, _ := strings.Cut(part, ";")
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if strings.ToLower(k) == "q" {
+				if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 && f <= 1 {
+					q = f
+				}
+			}
+		}
+		values = append(values, qValue{value, q})
+	}
+	sort.SliceStable(values, func(i, j int) bool { return values[i].q > values[j].q })
+	return values
+}
+
+func matchMediaType(rng, mediaType string) int {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	switch rng {
+	case mediaType:
+		return 2
+	case typ + "/*":
+		return 1
+	case "*/*", "*":
+		return 0
+	}
+	return -1
+}
+
+// returns the offer with the best quality in header 'Accept', or "" if none is acceptable.
+// without the header, all offers are acceptable (the first is returned)
+func bestMediaType(accept string, offers []string) string {
+	return bestQValue(accept, offers, matchMediaType)
+}
+
+// "json" -> "application/json", "html" -> "text/html"
+func normalizeMediaType(t string) string {
+	if strings.Contains(t, "/") {
+		return t
+	}
+	if mt := mime.TypeByExtension("." + t); mt != "" {
+		mt, _, _ = strings.Cut(mt, ";")
+		return mt
+	}
+	return t
+}
+
+/*
+Returns the type with the best quality in header 'Accept', or "" if none is acceptable.
+The types can be extensions ("json", "html"...). Without the header, returns the first type
+
+	// Accept: text/html, application/json;q=0.9
+	ctx.Request.Accepts("json", "html") // "html"
+	ctx.Request.Accepts("application/json", "text/plain") // "application/json"
+	ctx.Request.Accepts("image/png") // ""
+*/
+func (r *Request) Accepts(types ...string) string {
+	offers := make([]string, len(types))
+	for i, t := range types {
+		offers[i] = normalizeMediaType(t)
+	}
+	best := bestMediaType(r.Header.Get("Accept"), offers)
+	for i, o := range offers {
+		if o == best && best != "" {
+			return types[i]
+		}
+	}
+	return ""
+}
+
+/*
+Returns the language with the best quality in header 'Accept-Language', or "" if none is acceptable.
+The range "en" matches "en", "en-US", "en-GB"... Without the header, returns the first language
+
+	// Accept-Language: pt-BR, pt;q=0.9, en;q=0.8
+	lang := ctx.Request.AcceptsLanguages("en", "pt-BR", "es") // "pt-BR"
+	ctx.RenderTemplate(lang + "/index.html")
+*/
+func (r *Request) AcceptsLanguages(langs ...string) string {
+	return bestQValue(r.Header.Get("Accept-Language"), langs, func(rng, lang string) int {
+		switch {
+		case rng == lang:
+			return 2
+		case strings.HasPrefix(lang, rng+"-"):
+			return 1
+		case rng == "*":
+			return 0
+		}
+		return -1
+	})
+}
+
+// Returns the charset with the best quality in header 'Accept-Charset', or "" if none is acceptable
+//
+//	ctx.Request.AcceptsCharsets("utf-8", "iso-8859-1")
+func (r *Request) AcceptsCharsets(charsets ...string) string {
+	return bestQValue(r.Header.Get("Accept-Charset"), charsets, matchToken)
+}
+
+// Returns the encoding with the best quality in header 'Accept-Encoding', or "" if none is acceptable.
+// "identity" is acceptable, unless "identity;q=0" or "*;q=0"
+//
+//	// Accept-Encoding: gzip, br;q=0.9
+//	ctx.Request.AcceptsEncodings("br", "gzip") // "gzip"
+func (r *Request) AcceptsEncodings(encodings ...string) string {
+	header := r.Header.Get("Accept-Encoding")
+	ranges := parseQValues(header)
+	if header != "" && slices.IndexFunc(ranges, func(q qValue) bool { return q.value == "identity" || q.value == "*" }) < 0 {
+		header += ", identity;q=0.001" // the lowest quality
+	}
+	return bestQValue(header, encodings, matchToken)
+}
+
+func matchToken(rng, value string) int {
+	switch rng {
+	case value:
+		return 1
+	case "*":
+		return 0
+	}
+	return -1
+}
+
+// returns the offer with the best quality in header. 'match' returns the specificity of range for the offer (-1 doesn't match)
+func bestQValue(header string, offers []string, match func(rng, offer string) int) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	if strings.TrimSpace(header) == "" {
+		return offers[0]
+	}
+	ranges := parseQValues(header)
+	best, bestQ := "", 0.0
+	for _, o := range offers {
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			if s := match(r.value, strings.ToLower(o)); s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = o, q
+		}
+	}
+	return best
+}
+
+/*
+Exec the func of type with the best quality in header 'Accept' (the keys can be extensions).
+The key "default" is used if none is acceptable, else responds 406 Not Acceptable
+(with the error handler of 406, if exists)
+
+	func user(ctx *braza.Ctx) {
+		ctx.Negotiate(map[string]func(){
+			"html": func() { ctx.RenderTemplate("user.html", user) },
+			"json": func() { ctx.JSON(user, 200) },
+			"text/csv": func() { ctx.TEXT(user.CSV(), 200) },
+		})
+	}
+*/
+func (ctx *Ctx) Negotiate(handlers map[string]func()) {
+	types := make([]string, 0, len(handlers))
+	for t := range handlers {
+		if t != "default" {
+			types = append(types, t)
+		}
+	}
+	slices.Sort(types) // the map has no order: the ties are resolved always in the same way
+	ctx.header.Add("Vary", "Accept")
+
+	t := ctx.Request.Accepts(types...)
+	if t == "" {
+		if f, ok := handlers["default"]; ok {
+			f()
+			return
+		}
+		ctx.Abort(406)
+	}
+	ctx.header.Set("Content-Type", normalizeMediaType(t))
+	handlers[t]()
+}
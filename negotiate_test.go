@@ -0,0 +1,86 @@
+package braza
+
+import (
+	"testing"
+)
+
+func TestAcceptHeaders(t *testing.T) {
+	var rq *Request
+	app := newTestApp(t, nil, GET("/", func(ctx *Ctx) {
+		rq = ctx.Request
+		ctx.NoContent()
+	}))
+	request := func(headers ...string) *Request {
+		t.Helper()
+		expectStatus(t, doRequest(app, "GET", "/", "", headers...), 204)
+		return rq
+	}
+
+	r := request("Accept", "text/html, application/json;q=0.9")
+	if got := r.Accepts("json", "html"); got != "html" {
+		t.Errorf("Accepts: %q", got)
+	}
+	if got := r.Accepts("image/png"); got != "" {
+		t.Errorf("Accepts of a type not acceptable: %q", got)
+	}
+	r = request("Accept", "application/*;q=0.5, application/json")
+	if got := r.Accepts("application/xml", "application/json"); got != "application/json" {
+		t.Errorf("the specific range must win: %q", got)
+	}
+	if got := request().Accepts("json", "html"); got != "json" {
+		t.Errorf("without the header, the first type: %q", got)
+	}
+
+	r = request("Accept-Language", "pt-BR, pt;q=0.9, en;q=0.8")
+	if got := r.AcceptsLanguages("en", "pt-BR", "es"); got != "pt-BR" {
+		t.Errorf("AcceptsLanguages: %q", got)
+	}
+	if got := r.AcceptsLanguages("en-US", "es"); got != "en-US" {
+		t.Errorf("the range 'en' must match 'en-US': %q", got)
+	}
+
+	r = request("Accept-Encoding", "gzip, br;q=0.9")
+	if got := r.AcceptsEncodings("br", "gzip"); got != "gzip" {
+		t.Errorf("AcceptsEncodings: %q", got)
+	}
+	if got := r.AcceptsEncodings("zstd", "identity"); got != "identity" {
+		t.Errorf("identity is acceptable: %q", got)
+	}
+	r = request("Accept-Encoding", "gzip, identity;q=0")
+	if got := r.AcceptsEncodings("identity"); got != "" {
+		t.Errorf("identity;q=0 is not acceptable: %q", got)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	handlers := func(ctx *Ctx, withDefault bool) map[string]func() {
+		h := map[string]func(){
+			"html": func() { ctx.HTML("<b>joe</b>", 200) },
+			"json": func() { ctx.JSON(map[string]string{"name": "joe"}, 200) },
+		}
+		if withDefault {
+			h["default"] = func() { ctx.TEXT("joe", 200) }
+		}
+		return h
+	}
+	strict := GET("/strict", func(ctx *Ctx) { ctx.Negotiate(handlers(ctx, false)) })
+	strict.Name = "strict"
+	app := newTestApp(t, nil, strict, GET("/", func(ctx *Ctx) { ctx.Negotiate(handlers(ctx, true)) }))
+
+	rec := doRequest(app, "GET", "/strict", "", "Accept", "application/json")
+	expectStatus(t, rec, 200)
+	if rec.Body.String() != `{"name":"joe"}` || rec.Header().Get("Vary") != "Accept" {
+		t.Errorf("json: %q %v", rec.Body.String(), rec.Header())
+	}
+	rec = doRequest(app, "GET", "/strict", "", "Accept", "text/html")
+	if rec.Body.String() != "<b>joe</b>" {
+		t.Errorf("html: %q", rec.Body.String())
+	}
+	expectStatus(t, doRequest(app, "GET", "/strict", "", "Accept", "text/csv"), 406)
+
+	rec = doRequest(app, "GET", "/", "", "Accept", "text/csv")
+	expectStatus(t, rec, 200)
+	if rec.Body.String() != "joe" {
+		t.Errorf("default: %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,114 @@
+package braza
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+/*
+Problem Details for HTTP APIs (RFC 9457)
+
+	func createUser(ctx *braza.Ctx) {
+		...
+		ctx.Problem(&braza.Problem{
+			Status: 409,
+			Detail: "the email is already registered",
+			Extensions: map[string]any{"email": email},
+		})
+	}
+
+	// response:
+	// Content-Type: application/problem+json
+	// {"type":"about:blank","title":"Conflict","status":409,"detail":"the email is already registered","instance":"/users","email":"foo@bar.com"}
+*/
+type Problem struct {
+	Type     string // URI reference of the problem type (default 'about:blank')
+	Title    string // short summary of the problem type (default is the status text)
+	Status   int    // http status code (default 500)
+	Detail   string // explanation specific to this occurrence of the problem
+	Instance string // URI reference of this occurrence (default is the request path)
+
+	// extension members, written at the top level of the json document
+	//	map[string]any{"errors": []any{...}}
+	Extensions map[string]any
+}
+
+// Returns a new Problem with the title of status code
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return fmt.Sprintf("%d %s: %s", p.Status, p.Title, p.Detail)
+	}
+	return fmt.Sprintf("%d %s", p.Status, p.Title)
+}
+
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	m := map[string]any{}
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	m["type"] = p.Type
+	m["title"] = p.Title
+	m["status"] = p.Status
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	}
+	return json.Marshal(m)
+}
+
+func (p *Problem) setDefaults(rq *Request) {
+	if p.Status == 0 {
+		p.Status = 500
+	}
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Instance == "" && rq != nil {
+		p.Instance = rq.URL.Path
+	}
+}
+
+// write the problem in response, without abort
+func (r *Response) writeProblem(p *Problem) {
+	p.setDefaults(r.ctx.Request)
+	j, err := json.Marshal(p)
+	if err != nil {
+		panic(err)
+	}
+	r.Reset()
+	r.StatusCode = p.Status
+	r.header.Set("Content-Type", "application/problem+json")
+	r.Write(j)
+}
+
+// Write a 'application/problem+json' response and abort the request
+func (r *Response) Problem(p *Problem) {
+	r.writeProblem(p)
+	panic(ErrHttpAbort)
+}
+
+// the schema errors are json documents (c3po): keep them as json in the 'errors' member
+func schemaProblem(err error) *Problem {
+	errs, ok := schemaErrors(err)
+	if !ok && json.Unmarshal([]byte(err.Error()), &errs) != nil {
+		errs = err.Error()
+	}
+	p := NewProblem(400, "the request does not match the schema")
+	p.Extensions = map[string]any{"errors": errs}
+	return p
+}
@@ -0,0 +1,63 @@
+package braza
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func decodeProblem(t *testing.T, body []byte) map[string]any {
+	t.Helper()
+	m := map[string]any{}
+	if err := json.Unmarshal(body, &m); err != nil {
+		t.Fatalf("the problem is not json: %v (%s)", err, body)
+	}
+	return m
+}
+
+func TestProblemResponse(t *testing.T) {
+	app := newTestApp(t, nil, POST("/users", func(ctx *Ctx) {
+		ctx.Problem(&Problem{
+			Status:     409,
+			Detail:     "the email is already registered",
+			Extensions: map[string]any{"email": "foo@bar.com", "status": 200},
+		})
+	}))
+	rec := doRequest(app, "POST", "/users", "")
+	expectStatus(t, rec, 409)
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("Content-Type: %s", ct)
+	}
+	p := decodeProblem(t, rec.Body.Bytes())
+	want := map[string]any{
+		"type":     "about:blank",
+		"title":    "Conflict",
+		"status":   409.0, // the extensions don't override the standard members
+		"detail":   "the email is already registered",
+		"instance": "/users",
+		"email":    "foo@bar.com",
+	}
+	for k, v := range want {
+		if p[k] != v {
+			t.Errorf("%s: got %v, want %v", k, p[k], v)
+		}
+	}
+}
+
+func TestProblemDetailsForAborts(t *testing.T) {
+	app := newTestApp(t, &Config{ProblemDetails: true}, GET("/", func(ctx *Ctx) { ctx.Forbidden() }))
+
+	rec := doRequest(app, "GET", "/", "")
+	expectStatus(t, rec, 403)
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("Content-Type: %s", ct)
+	}
+	if p := decodeProblem(t, rec.Body.Bytes()); p["title"] != "Forbidden" || p["status"] != 403.0 {
+		t.Errorf("problem: %v", p)
+	}
+
+	rec = doRequest(app, "GET", "/missing", "")
+	expectStatus(t, rec, 404)
+	if p := decodeProblem(t, rec.Body.Bytes()); p["instance"] != "/missing" {
+		t.Errorf("problem: %v", p)
+	}
+}
@@ -0,0 +1,150 @@
+//go:build !windows
+
+package braza
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var reloadOnce sync.Once
+
+type filer interface {
+	File() (*os.File, error)
+}
+
+// on SIGHUP or SIGUSR2, starts a new process of the binary that inherits the listeners of all apps
+func watchReloadSignals() {
+	reloadOnce.Do(func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGHUP, syscall.SIGUSR2)
+		go func() {
+			for s := range sig {
+				l.warn.Printf("%s received, reloading the binary...", s)
+				if err := handoff(); err != nil {
+					l.err.Println("reload failed:", err)
+				}
+			}
+		}()
+	})
+}
+
+/*
+graceful reload:
+  - starts the new binary passing the listeners of all apps (mapStackApps)
+  - waits the new process be ready
+  - drains the current servers
+*/
+func handoff() error {
+	var (
+		apps    []*App
+		files   []*os.File
+		names   []string
+		timeout time.Duration
+	)
+	for _, app := range stackApps() {
+		if app.listener != nil {
+			apps = append(apps, app)
+		}
+	}
+	if len(apps) == 0 {
+		return errors.New("there are no listeners to hand off")
+	}
+
+	for _, app := range apps {
+		fl, ok := app.listener.(filer)
+		if !ok {
+			return fmt.Errorf("the listener of app '%s' can't be handed off", app.Name)
+		}
+		f, err := fl.File()
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		files = append(files, f)
+		names = append(names, escapeFdName(app.Name))
+		if app.GracefulTimeout > timeout {
+			timeout = app.GracefulTimeout
+		}
+	}
+
+	rd, wr, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+
+	bin, err := os.Executable()
+	if err != nil {
+		wr.Close()
+		return err
+	}
+	cmd := exec.Command(bin, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append(files, wr) // fd 3, 4, ... and the ready pipe as last one
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("%s=%d", envListenFds, len(files)),
+		fmt.Sprintf("%s=%s", envListenFdNames, strings.Join(names, ":")),
+		fmt.Sprintf("%s=%d", envReadyFd, listenFdsStart+len(files)),
+	)
+	err = cmd.Start()
+	wr.Close()
+	if err != nil {
+		return err
+	}
+
+	ready := make(chan error, 1)
+	go func() {
+		_, err := rd.Read(make([]byte, 1))
+		ready <- err
+	}()
+	select {
+	case err := <-ready:
+		if err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return fmt.Errorf("the new process exited before being ready: %w", err)
+		}
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		cmd.Wait()
+		return errors.New("timeout waiting for the new process")
+	}
+
+	l.warn.Printf("new process (pid %d) is ready, draining connections...", cmd.Process.Pid)
+	cmd.Process.Release()
+	for _, app := range apps {
+		app.draining.Store(true)
+		go app.drain()
+	}
+	return nil
+}
+
+// gracefully shutdown the servers of app, waiting for active connections
+func (app *App) drain() {
+	defer close(app.drained)
+	if ul, ok := app.listener.(*net.UnixListener); ok {
+		ul.SetUnlinkOnClose(false) // the socket file now belongs to the new process
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), app.GracefulTimeout)
+	defer cancel()
+
+	if app.h3Srv != nil {
+		app.h3Srv.Shutdown(ctx)
+	}
+	if err := app.Srv.Shutdown(ctx); err != nil {
+		l.err.Println(err)
+		app.Srv.Close()
+	}
+}
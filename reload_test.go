@@ -0,0 +1,93 @@
+//go:build !windows
+
+package braza
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFdNamesEscape(t *testing.T) {
+	names := []string{"api", "admin:v2", "100%", ""}
+	escaped := make([]string, len(names))
+	for i, n := range names {
+		escaped[i] = escapeFdName(n)
+	}
+	for i, n := range strings.Split(strings.Join(escaped, ":"), ":") {
+		if got := unescapeFdName(n); got != names[i] {
+			t.Errorf("name %d: got %q, want %q", i, got, names[i])
+		}
+	}
+}
+
+func TestStackAppOnce(t *testing.T) {
+	app := newTestApp(t, nil)
+	stackApp(app)
+	stackApp(app)
+	t.Cleanup(func() {
+		mapStackMu.Lock()
+		delete(mapStackApps, app.uuid)
+		mapStackMu.Unlock()
+	})
+	n := 0
+	for _, a := range stackApps() {
+		if a == app {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Fatalf("the app was registered %d times", n)
+	}
+}
+
+func TestDrainWaitsActiveRequests(t *testing.T) {
+	started := make(chan struct{})
+	app := newTestApp(t, &Config{Env: "production", Silent: true, DisableGracefulReload: true},
+		GET("/slow", func(ctx *Ctx) {
+			close(started)
+			time.Sleep(time.Millisecond * 100)
+			ctx.TEXT("done", 200)
+		}))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveTestApp(t, app, ln)
+
+	type result struct {
+		body string
+		err  error
+	}
+	res := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/slow")
+		if err != nil {
+			res <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		res <- result{string(b), err}
+	}()
+
+	<-started
+	app.draining.Store(true)
+	go app.drain()
+
+	r := <-res
+	if r.err != nil || r.body != "done" {
+		t.Fatalf("the active request was not drained: %q, %v", r.body, r.err)
+	}
+	select {
+	case <-app.drained:
+	case <-time.After(time.Second * 5):
+		t.Fatal("the drain didn't finish")
+	}
+	if _, err := http.Get("http://" + ln.Addr().String() + "/slow"); err == nil {
+		t.Error("the drained server is still accepting connections")
+	}
+}
@@ -0,0 +1,4 @@
+package braza
+
+// graceful reload by signals is not supported on windows
+func watchReloadSignals() {}
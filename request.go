@@ -4,41 +4,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"encoding/xml"
-	"io"
+	"errors"
+	"fmt"
 	"mime"
-	"mime/multipart"
 	"net"
 	"net/http"
 	"net/url"
+	"reflect"
 	"strings"
 
 	"github.com/gorilla/websocket"
-	"gopkg.in/yaml.v2"
 )
 
-func NewFile(p *multipart.Part) *File {
-	b, err := io.ReadAll(p)
-	if err != nil {
-		l.err.Panicln(err)
-	}
-	buf := bytes.NewBuffer(b)
-
-	return &File{
-		Filename:     p.FileName(),
-		ContentType:  p.Header.Get("Content-Type"),
-		ContentLeght: buf.Len(),
-		Stream:       buf,
-	}
-}
-
-type File struct {
-	Filename     string
-	ContentType  string
-	ContentLeght int
-	Stream       *bytes.Buffer
-}
-
 func NewRequest(req *http.Request, ctx *Ctx) *Request {
 	req.URL.Host = req.Host
 	rq := &Request{
@@ -51,14 +28,22 @@ func NewRequest(req *http.Request, ctx *Ctx) *Request {
 
 		ContentLength: int(req.ContentLength),
 
-		Body:     bytes.NewBuffer(nil),
-		PathArgs: map[string]string{},
-		Mime:     map[string]string{},
-		Form:     map[string]any{},
-		Files:    map[string][]*File{},
-		Cookies:  map[string]*http.Cookie{},
+		Body:       bytes.NewBuffer(nil),
+		PathArgs:   map[string]string{},
+		PathValues: map[string]any{},
+		Mime:       map[string]string{},
+		Form:       map[string]any{},
+		Files:      map[string][]*File{},
+		Cookies:    map[string]*http.Cookie{},
 
+		Host:   req.Host,
 		Header: req.Header,
+
+		TransferEncoding: req.TransferEncoding,
+
+		Proto:      req.Proto,
+		ProtoMajor: req.ProtoMajor,
+		ProtoMinor: req.ProtoMinor,
 	}
 
 	if host, port, err := net.SplitHostPort(req.Host); err == nil {
@@ -72,20 +57,41 @@ func NewRequest(req *http.Request, ctx *Ctx) *Request {
 	return rq
 }
 
+// update the request with a new *http.Request (ex: r.WithContext(...) in a middleware)
+func (r *Request) setRaw(req *http.Request) {
+	req.URL.Host = req.Host
+	r.raw = req
+	r.URL = req.URL
+	r.Method = req.Method
+	r.Header = req.Header
+	r.RemoteAddr = req.RemoteAddr
+	r.RequestURI = req.RequestURI
+	r.Host = req.Host
+	if host, port, err := net.SplitHostPort(req.Host); err == nil {
+		r.Host = host
+		r.Port = port
+	}
+}
+
 type Request struct {
 	raw *http.Request
 
 	ctx    *Ctx
 	Header http.Header
 
+	// the raw body, after Request.ReadBody or Request.ParseForm (empty for multipart bodies)
 	Body *bytes.Buffer
 	Method,
 	RemoteAddr,
 	RequestURI,
 	ContentType string
 
-	isWebsocket  bool
-	formIsParsed bool
+	isWebsocket    bool
+	formIsParsed   bool
+	bodyWasRead    bool
+	bodyIsLimited  bool
+	decodeInSchema bool            // the codec of body doesn't decode in Form
+	absentFields   map[string]bool // the fields of schema that are not in the request
 
 	ContentLength int
 
@@ -99,6 +105,14 @@ type Request struct {
 	Files    map[string][]*File
 	Cookies  map[string]*http.Cookie
 
+	// the query decoded in nested values, like a json body
+	//	"?tags=a&tags=b&user.name=joe" -> {"tags": ["a", "b"], "user": {"name": "joe"}}
+	QueryValues map[string]any
+
+	// the values of route variables, converted by the converters
+	//	ctx.Request.PathValues["id"].(int) // "/{id:int}"
+	PathValues map[string]any
+
 	TransferEncoding []string
 
 	Proto      string // "HTTP/1.0"
@@ -119,17 +133,17 @@ func (r *Request) parseHeaders() {
 	r.Mime = params
 	mi := r.ctx.MatchInfo
 	r.Query = r.URL.Query()
-	r.PathArgs = re.getUrlValues(mi.Route.Url, r.URL.Path)
-	if mi.Router.Subdomain != "" {
-		for k, w := range re.getSubdomainValues(mi.Router.Subdomain, r.URL.Host) {
-			r.PathArgs[k] = w
+	r.QueryValues = decodeValues(r.Query)
+	r.PathArgs, r.PathValues = mi.Route.pathValues(r.URL.Path)
+	if len(mi.Router.hosts) > 0 {
+		args, values := mi.Router.hostValues(r.Host)
+		for k, v := range args {
+			r.PathArgs[k] = v
+			r.PathValues[k] = values[k]
 		}
 	}
 
-	head := r.Header
-	if head.Get("Connection") == "Upgrade" && head.Get("Upgrade") == "websocket" {
-		r.isWebsocket = true
-	}
+	r.isWebsocket = websocket.IsWebSocketUpgrade(r.raw)
 }
 
 func (r *Request) parseCookies() {
@@ -142,52 +156,124 @@ func (r *Request) parseCookies() {
 	}
 }
 
+/*
+Parse the body in Request.Form (json, xml, yaml, form-urlencoded or multipart). The body is read
+only when needed: this is called before the route func (unless Config.DisableParseFormBody),
+so the middlewares that reject the request don't read the body
+
+	func auth(ctx *braza.Ctx) {
+		ctx.Request.ParseForm() // the body before the route func
+		token := ctx.Request.Form["token"]
+		...
+	}
+
+a malformed body is a 400 Bad Request, and a body larger than MaxBodySize is a 413.
+a valid json that is not a object (ex: a array of a bulk endpoint) leaves the Form empty: use ReadBody
+*/
 func (r *Request) ParseForm() {
-	if !r.formIsParsed {
-		ctx := r.ctx
-		switch {
-		case r.ContentType == "", strings.HasPrefix(r.ContentType, "application/json"):
-			json.Unmarshal(r.Body.Bytes(), &r.Form)
-		case strings.HasPrefix(r.ContentType, "application/xml"):
-			xml.Unmarshal(r.Body.Bytes(), &r.Form)
-		case strings.HasPrefix(r.ContentType, "application/yaml"):
-			yaml.Unmarshal(r.Body.Bytes(), &r.Form)
-		case strings.HasPrefix(r.ContentType, "application/x-www-form-urlencoded"):
-			v, err := url.ParseQuery(r.Body.String())
-			if err == nil {
-				for k, _v := range v {
-					r.Form[k] = _v[0]
-				}
+	if r.formIsParsed {
+		return
+	}
+	r.formIsParsed = true
+	ct := r.ContentType
+	if strings.HasPrefix(ct, "multipart/") {
+		r.parseMultipart()
+		return
+	}
+	body := r.ReadBody()
+	if len(bytes.TrimSpace(body)) == 0 {
+		return
+	}
+	var err error
+	switch {
+	case ct == "":
+		json.Unmarshal(body, &r.Form) // without content type, the body may not be a json
+	case strings.HasPrefix(ct, "application/x-www-form-urlencoded"):
+		var v url.Values
+		if v, err = url.ParseQuery(string(body)); err == nil {
+			for k, _v := range decodeValues(v) {
+				r.Form[k] = _v
 			}
-		case strings.HasPrefix(r.ContentType, "multipart/"):
-			mp := multipart.NewReader(r.Body, r.Mime["boundary"])
-			b := bytes.NewBufferString("")
-			for {
-				p, err := mp.NextPart()
-				if err == io.EOF {
-					break
-				}
-				if err != nil {
-					l.Error(err)
-					ctx.Response.BadRequest()
-				}
-				if p.FileName() != "" {
-					file := NewFile(p)
-					r.Files[p.FormName()] = append(r.Files[p.FormName()], file)
-				} else if p.FormName() != "" {
-					b.ReadFrom(p)
-					r.Form[p.FormName()] = b.String()
-					b.Reset()
-				}
+		}
+	default:
+		if codec, ok := r.ctx.App.getCodec(ct); ok {
+			err = codec.Unmarshal(body, &r.Form)
+			var typeErr *json.UnmarshalTypeError
+			if errors.Is(err, errors.ErrUnsupported) {
+				err = nil // ex: protobuf, decoded only in the schema
+				r.decodeInSchema = true
+			} else if errors.As(err, &typeErr) && json.Valid(body) {
+				err = nil // a array or a scalar: only the syntax errors are a 400
+				r.Form = map[string]any{}
+			}
+			if r.Form == nil {
+				r.Form = map[string]any{}
 			}
 		}
-		r.formIsParsed = true
+	}
+	if err != nil {
+		r.Form = map[string]any{}
+		msg := fmt.Sprintf("malformed body (%s): %v", ct, err)
+		if r.ctx.App.ProblemDetails {
+			r.ctx.Response.Problem(NewProblem(400, msg))
+		}
+		r.ctx.Response.JSON(map[string]string{"error": msg}, 400)
+	}
+}
+
+/*
+Returns the raw body of request. The body is read once (with the limit of MaxBodySize)
+and kept in Request.Body
+
+	func webhook(ctx *braza.Ctx) {
+		payload := ctx.Request.ReadBody()
+		if !validSignature(payload, ctx.Request.Header.Get("X-Signature")) {
+			ctx.Unauthorized()
+		}
+		...
+	}
+*/
+func (r *Request) ReadBody() []byte {
+	if !r.bodyWasRead {
+		r.limitBody()
+		if r.ContentLength > 0 {
+			r.Body.Grow(r.ContentLength)
+		}
+		r.bodyWasRead = true
+		if _, err := r.Body.ReadFrom(r.raw.Body); err != nil {
+			r.bodyError(err)
+		}
+	}
+	return r.Body.Bytes()
+}
+
+// the MaxBodySize of route, or of app (MaxUploadSize for multipart). -1 is unlimited
+func (r *Request) maxBodySize() int64 {
+	if route := r.ctx.MatchInfo.Route; route != nil && route.MaxBodySize != 0 {
+		return route.MaxBodySize
+	}
+	if strings.HasPrefix(r.ContentType, "multipart/") {
+		return r.ctx.App.MaxUploadSize
+	}
+	return r.ctx.App.MaxBodySize
+}
+
+// apply the MaxBodySize in the body (http.MaxBytesReader), before the first read
+func (r *Request) limitBody() {
+	if r.bodyIsLimited {
+		return
+	}
+	r.bodyIsLimited = true
+	if max := r.maxBodySize(); max > 0 {
+		if r.ContentLength > 0 && int64(r.ContentLength) > max {
+			r.ctx.Response.Abort(http.StatusRequestEntityTooLarge)
+		}
+		r.raw.Body = http.MaxBytesReader(r.ctx, r.raw.Body, max)
 	}
 }
 
+// read and parse the body, before the route func
 func (r *Request) parseBody() {
-	r.Body.Grow(r.ContentLength)
-	r.Body.ReadFrom(r.raw.Body)
 	if !r.ctx.App.DisableParseFormBody {
 		r.ParseForm()
 	}
@@ -198,18 +284,45 @@ func (r *Request) parseSchema() {
 		return
 	}
 	sch := r.ctx.SchemaFielder
-	nSch, err := MountSchemaFromRequest(sch, r)
+	nSch, err := r.decodeSchema()
+	if nSch == nil && err == nil {
+		nSch, err = MountSchemaFromRequest(sch, r)
+	}
+	// the rules are checked also in a partial schema: all the failures are returned
+	var errs SchemaError
+	if err == nil || errors.As(err, &errs) {
+		if vErr := r.ctx.App.validate(nSch, r.absentFields); vErr != nil {
+			errs = append(errs, vErr.(SchemaError)...)
+			err = errs
+		}
+	}
 	if err != nil {
+		if r.ctx.App.ProblemDetails {
+			r.ctx.Response.Problem(schemaProblem(err))
+		}
 		r.ctx.Response.JSON(err, 400)
 	}
 	r.ctx.Schema = nSch
 }
 
+// the codecs that only decode in types (ex: protobuf) decode the body directly in the schema
+func (r *Request) decodeSchema() (any, error) {
+	t := reflect.TypeOf(r.ctx.SchemaFielder.Schema)
+	if !r.decodeInSchema || t == nil || t.Kind() != reflect.Pointer {
+		return nil, nil
+	}
+	codec, _ := r.ctx.App.getCodec(r.ContentType)
+	sch := reflect.New(t.Elem()).Interface()
+	if err := codec.Unmarshal(r.ReadBody(), sch); err != nil {
+		return nil, err
+	}
+	return sch, nil
+}
+
 func (r *Request) parse() {
 	r.parseHeaders()
+	r.ctx.resolveTenant()
 	r.parseCookies()
-	r.parseBody()
-	r.parseSchema()
 }
 
 // Returns the current url
@@ -222,7 +335,7 @@ func (r *Request) RequestURL() string {
 	for k, v := range r.Query {
 		args = append(args, k, v[0])
 	}
-	return r.ctx.App.UrlFor(route.Name, true, args...)
+	return r.ctx.UrlFor(route.Name, true, args...)
 }
 
 /*
@@ -251,7 +364,7 @@ URL Builder
 	}
 */
 func (r *Request) UrlFor(name string, external bool, args ...string) string {
-	return r.ctx.App.UrlFor(name, external, args...)
+	return r.ctx.UrlFor(name, external, args...)
 }
 
 func (r *Request) Ctx() *Ctx                          { return r.ctx }
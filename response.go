@@ -3,7 +3,6 @@ package braza
 import (
 	"bufio"
 	"bytes"
-	"context"
 	"encoding/json"
 	"fmt"
 	"html/template"
@@ -13,6 +12,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sync"
 
 	"github.com/ethoDomingues/c3po"
@@ -38,14 +38,16 @@ type Response struct {
 	StatusCode int
 	ctx        *Ctx
 	raw        http.ResponseWriter
+	sent       bool // the response was written directly in 'raw' (sse, websocket...)
 }
 
-func (r Response) SetHeader(h http.Header)        { r.header = h }
-func (r Response) Header() http.Header            { return r.header }
-func (r Response) Write(b []byte) (int, error)    { return r.Buffer.Write(b) }
-func (r Response) WriteHeader(statusCode int)     { r.StatusCode = statusCode }
+func (r *Response) SetHeader(h http.Header)       { r.header = h }
+func (r *Response) Header() http.Header           { return r.header }
+func (r *Response) Write(b []byte) (int, error)   { return r.Buffer.Write(b) }
+func (r *Response) WriteHeader(statusCode int)    { r.StatusCode = statusCode }
 func (r *Response) SetCookie(cookie *http.Cookie) { SetCookie(r.header, cookie) }
 func (r *Response) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.sent = true
 	return r.raw.(http.Hijacker).Hijack()
 }
 
@@ -96,6 +98,9 @@ func (r *Response) parseHeaders() {
 	} else if ctx.Request.Method == "HEAD" {
 		r.Reset()
 	}
+	if h3 := ctx.App.h3Srv; h3 != nil && ctx.Request.ProtoMajor < 3 {
+		h3.SetQUICHeaders(h) // Alt-Svc: h3=":port"
+	}
 }
 
 func (r *Response) textCode(code int) {
@@ -103,7 +108,7 @@ func (r *Response) textCode(code int) {
 	if statusText == "" {
 		panic(fmt.Errorf("unknown status code:'%d'", code))
 	}
-	r.ctx.backCtx = context.WithValue(r.ctx.backCtx, abortCode(1), code)
+	r.ctx.abortCode = code
 	panic(ErrHttpAbort)
 }
 
@@ -126,11 +131,16 @@ func (r *Response) JSON(body any, code int) {
 	if b, ok := body.(string); ok {
 		r.WriteString(b)
 		panic(ErrHttpAbort)
+	} else if p, ok := body.(*Problem); ok {
+		if p.Status == 0 {
+			p.Status = code
+		}
+		r.Problem(p)
 	} else if b, ok := body.(error); ok {
 		r.WriteString(b.Error())
 		panic(ErrHttpAbort)
 	} else if b, ok := body.(Jsonify); ok {
-		j, err := json.Marshal(b.ToJson())
+		j, err := json.Marshal(r.filterBody(b.ToJson(), code))
 		if err != nil {
 			panic(err)
 		}
@@ -138,7 +148,7 @@ func (r *Response) JSON(body any, code int) {
 		panic(ErrHttpAbort)
 	}
 
-	j, err := json.Marshal(body)
+	j, err := json.Marshal(r.filterBody(body, code))
 	if err != nil {
 		panic(err)
 	}
@@ -147,6 +157,29 @@ func (r *Response) JSON(body any, code int) {
 	panic(ErrHttpAbort)
 }
 
+// with Config.EnforceRespSchema, returns the body decoded in the RespSchema of status code (the undeclared fields are dropped)
+func (r *Response) filterBody(body any, code int) any {
+	ctx := r.ctx
+	sch, ok := ctx.respSchema[code]
+	if !ctx.App.EnforceRespSchema || !ok || sch == nil {
+		return body
+	}
+	j, err := json.Marshal(body)
+	if err != nil {
+		panic(err)
+	}
+	t := reflect.TypeOf(sch)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	v := reflect.New(t)
+	if err := json.Unmarshal(j, v.Interface()); err != nil {
+		// a type mismatch is a bug of route: never send a body that can't be filtered
+		panic(fmt.Errorf("route '%s': the response %d doesn't match the RespSchema '%s': %v", ctx.MatchInfo.Route.Name, code, t, err))
+	}
+	return v.Interface()
+}
+
 func (r *Response) TEXT(body any, code int) {
 	r.Reset()
 	r.StatusCode = code
@@ -186,8 +219,9 @@ func (r *Response) RenderTemplate(tmpl string, data ...any) {
 		lenFile int
 	)
 
-	if _t, ok = htmlTemplates.Load(app.Name + ":" + tmpl); !ok || (app.Env == "development" && !app.DisableTemplateReloader) {
-		pa := filepath.Join(app.TemplateFolder, tmpl)
+	key := app.Name + ":" + r.ctx.tenantID() + ":" + tmpl
+	if _t, ok = htmlTemplates.Load(key); !ok || (app.Env == "development" && !app.DisableTemplateReloader) {
+		pa := r.ctx.templateFile(tmpl)
 		_, err := os.Stat(pa)
 		if err != nil {
 			if app.Env == "development" {
@@ -203,7 +237,7 @@ func (r *Response) RenderTemplate(tmpl string, data ...any) {
 			Parse(string(f))
 		r.CheckErr(err)
 		lenFile = len(f)
-		htmlTemplates.Store(r.ctx.App.Name+":"+tmpl, t)
+		htmlTemplates.Store(key, t)
 	} else {
 		t = _t.(*template.Template)
 	}
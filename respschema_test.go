@@ -0,0 +1,41 @@
+package braza
+
+import (
+	"net/http"
+	"testing"
+)
+
+type testUser struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	PasswordHash string `json:"passwordHash"`
+}
+
+type testUserOut struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestRespSchemaDropsUndeclaredFields(t *testing.T) {
+	for _, env := range []string{"development", "production"} {
+		app := newTestApp(t, &Config{Env: env, EnforceRespSchema: true}, &Route{
+			Url:        "/user",
+			Func:       func(ctx *Ctx) { ctx.JSON(testUser{1, "joe", "secret"}, 200) },
+			RespSchema: RespSchema{200: &testUserOut{}},
+		})
+		rec := doRequest(app, "GET", "/user", "")
+		expectStatus(t, rec, http.StatusOK)
+		if got := rec.Body.String(); got != `{"id":1,"name":"joe"}` {
+			t.Errorf("%s: body: %s", env, got)
+		}
+	}
+}
+
+func TestRespSchemaTypeMismatch(t *testing.T) {
+	app := newTestApp(t, &Config{EnforceRespSchema: true}, &Route{
+		Url:        "/user",
+		Func:       func(ctx *Ctx) { ctx.JSON(map[string]any{"id": "not a number"}, 200) },
+		RespSchema: RespSchema{200: &testUserOut{}},
+	})
+	expectStatus(t, doRequest(app, "GET", "/user", ""), http.StatusInternalServerError)
+}
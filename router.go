@@ -3,7 +3,7 @@ package braza
 import (
 	"fmt"
 	"path/filepath"
-	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/gorilla/websocket"
@@ -22,36 +22,178 @@ type Router struct {
 	Name        string
 	Routes      []*Route
 	Prefix      string
-	Subdomain   string // only {sub} or {sub:int}
+	Subdomain   string // subdomain of servernames: "api", "{tenant}", "{id:int}.users", "*", "**"...
+	Host        string // full host, independent of servernames (custom domains): "{shop}.example.org", "**"...
 	WsUpgrader  *websocket.Upgrader
 	Middlewares []Func
 	StrictSlash bool
 
-	main           bool
-	routesByName   map[string]*Route
-	subdomainRegex []*regexp.Regexp
-	errHandlers    map[int]Func
+	main         bool
+	parent       *Router
+	children     []*Router
+	inherited    bool
+	routesByName map[string]*Route
+	hosts        []*hostPattern
+	prefix       []*urlSegment // the compiled Prefix
+	errHandlers  map[int]Func
 }
 
-func (r *Router) compileSub() {
-	sub := r.Subdomain
-	subSplit := strings.Split(sub, ".")
-	for _, str := range subSplit {
-		if str == "" {
-			continue
+/*
+Register sub routers. The sub router inherits the prefix, middlewares, cors, subdomain and error handlers of this router
+
+	api := braza.NewRouter("api")
+	api.Prefix = "/api"
+
+	v1 := braza.NewRouter("v1")
+	v1.Prefix = "/v1"
+	v1.GET("/users", users) // url: "/api/v1/users", name: "api.v1.users"
+
+	api.Mount(v1)
+	app.Mount(api)
+*/
+func (r *Router) Mount(routers ...*Router) {
+	for _, child := range routers {
+		if child.Name == "" {
+			panic(fmt.Errorf("the routers must be named"))
+		}
+		if child.parent != nil {
+			panic(fmt.Errorf("router '%s' already mounted in router '%s'", child.Name, child.parent.Name))
 		}
-		if re.isVar.MatchString(str) {
-			str = re.str.ReplaceAllString(str, `([a-zA-Z0-9\-\_]+)`)
-			str = re.digit.ReplaceAllString(str, `(\d+)`)
-			if re.isVar.MatchString(str) {
-				panic(fmt.Errorf("only 'str' and 'int' are allowed in dynamic subdomains - Router:'%s', Subdomain:'%s'", r.Name, r.Subdomain))
+		for _, c := range r.children {
+			if c.Name == child.Name {
+				panic(fmt.Errorf("router '%s' already regitered in router '%s'", child.Name, r.Name))
 			}
 		}
-		r.subdomainRegex = append(r.subdomainRegex, regexp.MustCompile(str))
+		child.parent = r
+		r.children = append(r.children, child)
+	}
+}
+
+/*
+Create and mount a sub router with prefix. The name of sub router is the prefix without slashes
+
+	app.Group("/admin", func(admin *braza.Router) {
+		admin.Middlewares = []braza.Func{isAdmin}
+		admin.GET("/users", users) // url: "/admin/users", name: "admin.users"
+	})
+*/
+func (r *Router) Group(prefix string, f func(g *Router)) *Router {
+	names := []string{}
+	for _, str := range strings.Split(prefix, "/") {
+		if str != "" {
+			names = append(names, re.getVarName(str))
+		}
+	}
+	if len(names) == 0 {
+		panic(fmt.Errorf("the group of router '%s' needs a prefix", r.Name))
+	}
+	g := NewRouter(strings.Join(names, "."))
+	g.Prefix = prefix
+	if f != nil {
+		f(g)
+	}
+	r.Mount(g)
+	return g
+}
+
+// apply the settings of parent in sub router
+func (r *Router) inherit(p *Router) {
+	if r.inherited {
+		return
+	}
+	r.inherited = true
+
+	if p.Name != "" {
+		r.Name = p.Name + "." + r.Name
+	}
+	if p.Prefix != "" {
+		if r.Prefix != "" && !strings.HasPrefix(r.Prefix, "/") {
+			panic(fmt.Errorf("Router '%v' Prefix must start with slash or be a null string ", r.Name))
+		}
+		r.Prefix = strings.TrimSuffix(p.Prefix, "/") + r.Prefix
+	}
+	r.Middlewares = slices.Concat(p.Middlewares, r.Middlewares)
+	if r.Cors == nil {
+		r.Cors = p.Cors
+	}
+	if r.Subdomain == "" && r.Host == "" {
+		r.Subdomain = p.Subdomain
+		r.Host = p.Host
+	}
+	if r.WsUpgrader == nil {
+		r.WsUpgrader = p.WsUpgrader
+	}
+	if p.StrictSlash {
+		r.StrictSlash = true
+	}
+	for code, h := range p.errHandlers {
+		if r.errHandlers == nil {
+			r.errHandlers = map[int]Func{}
+		}
+		if _, ok := r.errHandlers[code]; !ok {
+			r.errHandlers[code] = h
+		}
+	}
+}
+
+// returns all sub routers (the most specific first) and the router
+func (r *Router) flatten() []*Router {
+	routers := []*Router{}
+	for _, child := range r.children {
+		child.inherit(r)
+		routers = append(routers, child.flatten()...)
+	}
+	return append(routers, r)
+}
+
+/*
+compile the host patterns of router:
+  - Host: the host
+  - Subdomain: the subdomain of each servername
+  - else: the servernames (or any host, if the app doesn't have servernames)
+*/
+func (r *Router) compileHosts(app *App) {
+	r.hosts = nil
+	patterns := []string{}
+	switch {
+	case r.Host != "":
+		patterns = append(patterns, r.Host)
+	case r.Subdomain != "":
+		if len(app.servernames) == 0 {
+			panic(fmt.Errorf("to use subdomains you need to first add a ServerName in the app. Router:'%s'", r.Name))
+		}
+		for _, srv := range app.servernames {
+			patterns = append(patterns, r.Subdomain+"."+srv)
+		}
+	default:
+		patterns = app.servernames
+	}
+	for _, p := range patterns {
+		r.hosts = append(r.hosts, app.compileHost(r.Name, p))
+	}
+}
+
+// the Host or Subdomain, for logs
+func (r *Router) hostName() string {
+	if r.Host != "" {
+		return r.Host
+	}
+	return r.Subdomain
+}
+
+// the routers with less specific hosts are matched last
+func (r *Router) hostRank() int {
+	if len(r.hosts) == 0 {
+		return 1000 // any host
+	}
+	rank := r.hosts[0].rank
+	for _, h := range r.hosts {
+		rank = min(rank, h.rank)
 	}
+	return rank
 }
 
-func (r *Router) parseRoute(route *Route) {
+func (r *Router) parseRoute(route *Route, app *App) {
 	if route.Name == "" {
 		if route.Func == nil {
 			panic("the route needs to be named or have a 'Route.Func'")
@@ -76,13 +218,13 @@ func (r *Router) parseRoute(route *Route) {
 
 	route.simpleUrl = route.Url
 	route.Url = filepath.Join(r.Prefix, route.Url)
-	route.parse()
+	route.parse(app)
 	r.routesByName[route.Name] = route
 	route.router = r
 	route.parsed = true
 }
 
-func (r *Router) parse(servername string) {
+func (r *Router) parse(app *App) {
 	if r.routesByName == nil {
 		r.routesByName = map[string]*Route{}
 	}
@@ -90,22 +232,17 @@ func (r *Router) parse(servername string) {
 	if r.Name == "" && !r.main {
 		panic(fmt.Errorf("the routers must be named"))
 	}
-	if r.Subdomain != "" {
-		if servername == "" {
-			panic(fmt.Errorf("to use subdomains you need to first add a ServerName in the app. Router:'%s'", r.Name))
-		}
-		r.compileSub()
-	}
-	if servername != "" {
-		srvSplit := strings.Split(servername, ".")
-		for _, s := range srvSplit {
-			r.subdomainRegex = append(r.subdomainRegex, regexp.MustCompile(s)) // if nao tiver subdomain, ainda precisa usar o servername
+	r.compileHosts(app)
+	r.prefix = nil
+	for _, str := range strings.Split(strings.Trim(r.Prefix, "/"), "/") {
+		if str != "" {
+			r.prefix = append(r.prefix, app.parseUrlSegment(r.Name, str))
 		}
 	}
 
 	for _, route := range r.Routes {
 		if !route.parsed {
-			r.parseRoute(route)
+			r.parseRoute(route, app)
 		}
 		r.routesByName[route.Name] = route
 	}
@@ -114,19 +251,49 @@ func (r *Router) parse(servername string) {
 /*
  */
 
-func (r *Router) match(ctx *Ctx) bool {
-	rq := ctx.Request
-	if len(r.subdomainRegex) > 0 {
-		subSplit := strings.Split(rq.Host, ".")
-		if len(subSplit) != len(r.subdomainRegex) {
+func (r *Router) matchHost(ctx *Ctx) bool {
+	if len(r.hosts) == 0 {
+		return true
+	}
+	for _, h := range r.hosts {
+		if _, _, ok := h.match(ctx.Request.Host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// reports if the url starts with the Prefix of router (the variables are matched by their converters)
+func (r *Router) matchPrefix(path string) bool {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, seg := range r.prefix {
+		if seg.isPath {
+			return true
+		}
+		if i >= len(parts) || parts[i] == "" {
+			return seg.optional
+		}
+		if !seg.regex.MatchString(parts[i]) {
 			return false
 		}
-		for i, s := range r.subdomainRegex {
-			if !s.MatchString(subSplit[i]) { // regex do not work => create a new e replace in url too
-				return false
-			}
+	}
+	return true
+}
+
+// returns the values of host variables: {tenant}.example.com -> {"tenant": "foo"}
+func (r *Router) hostValues(host string) (map[string]string, map[string]any) {
+	for _, h := range r.hosts {
+		if args, values, ok := h.match(host); ok {
+			return args, values
 		}
 	}
+	return map[string]string{}, map[string]any{}
+}
+
+func (r *Router) match(ctx *Ctx) bool {
+	if !r.matchHost(ctx) {
+		return false
+	}
 
 	for _, route := range r.Routes {
 		if route.match(ctx) {
@@ -137,6 +304,21 @@ func (r *Router) match(ctx *Ctx) bool {
 	return false
 }
 
+/*
+Custom Http Error Handler of router (and of your sub routers). Also used in 404 and 405 errors of urls in the router prefix
+
+	api := braza.NewRouter("api")
+	api.ErrorHandler(404, func(ctx *braza.Ctx) {
+		ctx.JSON(map[string]any{"error": "not found"}, 404)
+	})
+*/
+func (r *Router) ErrorHandler(statusCode int, f Func) {
+	if r.errHandlers == nil {
+		r.errHandlers = map[int]Func{}
+	}
+	r.errHandlers[statusCode] = f
+}
+
 /*
  */
 func (r *Router) AddRoute(routes ...*Route) {
@@ -0,0 +1,91 @@
+package braza
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRouterErrorHandlerWithPrefixVariables(t *testing.T) {
+	api := NewRouter("api")
+	api.Prefix = "/{tenant}/api/{version:int}"
+	api.GET("/users", func(ctx *Ctx) { ctx.TEXT("users", 200) })
+	api.ErrorHandler(404, func(ctx *Ctx) { ctx.TEXT("api not found", 404) })
+
+	app := NewApp(&Config{DisableStatic: true})
+	app.Mount(api)
+	app.Build()
+
+	rec := doRequest(app, "GET", "/acme/api/1/missing", "")
+	expectStatus(t, rec, 404)
+	if rec.Body.String() != "api not found" {
+		t.Errorf("the error handler of router was not used: %q", rec.Body.String())
+	}
+	// the converter of prefix doesn't match: not a url of router
+	rec = doRequest(app, "GET", "/acme/api/v1/missing", "")
+	expectStatus(t, rec, 404)
+	if rec.Body.String() == "api not found" {
+		t.Error("the error handler of router was used out of its prefix")
+	}
+}
+
+func listUsers(ctx *Ctx) { ctx.TEXT("users", 200) }
+
+func TestNestedRouters(t *testing.T) {
+	mark := func(s string) Func {
+		return func(ctx *Ctx) {
+			ctx.Header().Add("X-Mids", s)
+			ctx.Next()
+		}
+	}
+	api := NewRouter("api")
+	api.Prefix = "/api"
+	api.Middlewares = []Func{mark("api")}
+	api.ErrorHandler(404, func(ctx *Ctx) { ctx.TEXT("api not found", 404) })
+
+	v1 := NewRouter("v1")
+	v1.Prefix = "/v1"
+	v1.Middlewares = []Func{mark("v1")}
+	v1.GET("/users", listUsers)
+	api.Mount(v1)
+
+	app := NewApp(&Config{DisableStatic: true})
+	app.Group("/admin", func(admin *Router) {
+		admin.Middlewares = []Func{mark("admin")}
+		admin.GET("/stats", func(ctx *Ctx) { ctx.TEXT("stats", 200) })
+	})
+	app.Mount(api)
+	app.Build()
+
+	rec := doRequest(app, "GET", "/api/v1/users", "")
+	expectStatus(t, rec, 200)
+	if mids := strings.Join(rec.Header().Values("X-Mids"), ","); mids != "api,v1" {
+		t.Errorf("middlewares: got %q, want the parent first", mids)
+	}
+	rec = doRequest(app, "GET", "/admin/stats", "")
+	expectStatus(t, rec, 200)
+	if mids := strings.Join(rec.Header().Values("X-Mids"), ","); mids != "admin" {
+		t.Errorf("middlewares of group: %q", mids)
+	}
+
+	// the sub router inherits the error handlers of parent
+	rec = doRequest(app, "GET", "/api/v1/missing", "")
+	expectStatus(t, rec, 404)
+	if rec.Body.String() != "api not found" {
+		t.Errorf("the error handler of parent was not inherited: %q", rec.Body.String())
+	}
+
+	if url := app.UrlFor("api.v1.listUsers", false); url != "/api/v1/users" {
+		t.Errorf("UrlFor: %q", url)
+	}
+}
+
+func TestMountTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("a router mounted in two routers must panic")
+		}
+	}()
+	v1 := NewRouter("v1")
+	NewRouter("a").Mount(v1)
+	NewRouter("b").Mount(v1)
+}
@@ -1,11 +1,14 @@
 package braza
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
-	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/ethoDomingues/c3po"
 )
@@ -26,6 +29,9 @@ example:
 		Pass string `braza:"in=auth,name=password"`
 		Limit int `braza:"in=query"` // /path/search?limit=1&offset=2
 		Offset int `braza:"in=query"` // /path/search?limit=1&offset=2
+		Tags []string `braza:"in=query"` // ?tags=a&tags=b
+		Items []Item `braza:"in=body"` // items[0].name=a&items[1].name=b (json, form or multipart)
+		Filter map[string]string `braza:"in=query"` // ?filter[status]=open or ?filter.status=open
 
 		Text string `braza:"in=body"`
 		Text2 string  // deafult is 'in=body'.
@@ -41,9 +47,27 @@ example:
 		u := ctx.Schema.(*Schema)
 		...
 	}
+
+	// or with a typed handler (see braza.Handle)
+	braza.Handle("GET", "/{bar:int}", func(ctx *braza.Ctx, u *Schema) (*Foo, error) {...})
 */
 type Schema any
 
+/*
+the types of response bodies by status code. with Config.EnforceRespSchema, the json bodies
+are filtered by the type of status code (ex: the fields not declared, like password hashes, are dropped)
+
+	type UserOut struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	Route{
+		Url:        "/users/{id:int}",
+		Func:       getUser, // ctx.JSON(user, 200) -> {"id":1,"name":"joe"}
+		RespSchema: braza.RespSchema{200: &UserOut{}},
+	}
+*/
 type RespSchema map[int]any
 
 type Meth struct {
@@ -62,9 +86,20 @@ type Route struct {
 			"/batata"	// match literal "/batata"
 			"/{name}"	// match any string (ex: batata1234)
 			"/{id:int}"	// match on numbers (ex: 12345)
+			"/{price:float}"	// match on decimals (ex: 12.5)
+			"/{id:uuid}"	// match on uuids (ex: 6ba7b810-9dad-11d1-80b4-00c04fd430c8)
+			"/{title:slug}"	// match on slugs (ex: hello-world)
+			"/{code:re:[a-z]{3}}"	// match the regex (ex: abc)
 			"/{path:*}"	|| "/{path:path}" // match anything
 			"/{var1:int}/{var2}/{var3}" // is allowed
-			`/(\d+)` // regex work too
+			"/posts/{page:int?}"	// optional segment (ex: /posts or /posts/2)
+			"/posts/{page:int=1}"	// optional with default value (ex: /posts -> page is 1)
+
+		the raw regex segments of old versions (`/(\d+)`) still match, but UrlFor can't build them
+		(the segment is written literally): use "/{id:re:\d+}"
+
+		only the last segments can be optional. the converted values are in ctx.Request.PathValues (int, float64, uuid.UUID...)
+		and the strings in ctx.Request.PathArgs. custom converters: App.RegisterConverter
 	*/
 	Url string
 
@@ -120,19 +155,38 @@ type Route struct {
 	//		[]Func{	GetUser, HasAUth,...
 	Middlewares []Func
 
+	/*
+		max duration of request: cancels the context (ctx.Done()) and responds 503 Service Unavailable
+		(like http.TimeoutHandler; 504 is not used), with the error handler of 503, if exists.
+
+		the timeout is cooperative: the 503 is written when the handler returns, so the handlers must
+		observe the context. a handler that ignores ctx.Done() holds the response until it finishes
+
+			func search(ctx *braza.Ctx) {
+				rows, err := db.QueryContext(ctx, "SELECT ...")
+				...
+	*/
+	Timeout time.Duration
+
+	// max size of request body on this route (413 Request Entity Too Large). overrides Config.MaxBodySize and Config.MaxUploadSize, -1 is unlimited
+	MaxBodySize int64
+
 	parsed      bool
 	router      *Router
-	urlRegex    []*regexp.Regexp
+	hub         *Hub // of websocket routes (braza.WS)
+	segments    []*urlSegment
+	required    int // number of segments that are not optional
 	hasSufix    bool
 	isStatic    bool
 	simpleUrl   string
 	simpleName  string
-	isUrlPrefix bool
+	isUrlPrefix bool // the last segment is a {path:*}
 }
 
-func (r *Route) compileUrl() {
+func (r *Route) compileUrl(app *App) {
 	uri := r.Url
-	isPrefix := false
+	r.segments = nil
+	r.required = 0
 	r.hasSufix = strings.HasSuffix(r.simpleUrl, "/")
 	if uri != "" && uri != "/" {
 		uri = strings.TrimPrefix(strings.TrimSuffix(uri, "/"), "/")
@@ -142,26 +196,28 @@ func (r *Route) compileUrl() {
 			if str == "" {
 				continue
 			}
-			if isPrefix {
+			if r.isUrlPrefix {
 				log.Panicf("Url Variable Invalid: '%s'", str)
 			}
-			if re.all.MatchString(str) {
-				isPrefix = true
-			}
-			if re.dot2.MatchString(str) {
-				str = re.dot2.ReplaceAllString(str, "/") // -> /../../home = /////home
-			}
-			if re.slash2.MatchString(str) {
-				str = re.slash2.ReplaceAllString(str, "/") // -> /////home = /home
+			if !isUrlVar(str) {
+				if re.dot2.MatchString(str) {
+					str = re.dot2.ReplaceAllString(str, "/") // -> /../../home = /////home
+				}
+				if re.slash2.MatchString(str) {
+					str = re.slash2.ReplaceAllString(str, "/") // -> /////home = /home
+				}
 			}
-
-			if re.isVar.MatchString(str) {
-				str = re.str.ReplaceAllString(str, `(([\x00-\x7F]+)([^\\\/\s]+)|\d+)`) // This expression will search for non-ASCII values:
-				str = re.digit.ReplaceAllString(str, `(\d+)`)
+			seg := app.parseUrlSegment(r.Name, str)
+			if seg.isPath {
+				r.isUrlPrefix = true
 			}
-			if !isPrefix {
-				r.urlRegex = append(r.urlRegex, regexp.MustCompile(fmt.Sprintf("^%s$", str)))
+			if !seg.optional {
+				if r.required < len(r.segments) {
+					panic(fmt.Errorf("Route '%s': only the last segments can be optional: '%s'", r.Name, str))
+				}
+				r.required++
 			}
+			r.segments = append(r.segments, seg)
 		}
 	}
 	if r.hasSufix {
@@ -169,10 +225,9 @@ func (r *Route) compileUrl() {
 			r.Url = r.Url + "/"
 		}
 	}
-	r.isUrlPrefix = isPrefix
 }
 
-func (r *Route) compileMethods() {
+func (r *Route) compileMethods(app *App) {
 	ctrl := MapCtrl{"OPTIONS": &Meth{}}
 
 	// allow Route{URL:"/",Name:"index",Func:func()} with method default "GET"
@@ -185,7 +240,11 @@ func (r *Route) compileMethods() {
 		if !reMethods.MatchString(v) {
 			l.err.Fatalf("route '%s' has invalid Request Method: '%s'", r.Name, verb)
 		}
+		if m.RespSchema == nil {
+			m.RespSchema = r.RespSchema
+		}
 		if m.Schema != nil {
+			app.checkRules(m.Schema)
 			sch := c3po.ParseSchemaWithTag("braza", m.Schema)
 			m.SchemaFielder = sch
 		}
@@ -202,10 +261,12 @@ func (r *Route) compileMethods() {
 
 		if _, ok := r.MapCtrl[v]; !ok {
 			r.MapCtrl[v] = &Meth{
-				Func: r.Func,
+				Func:       r.Func,
+				RespSchema: r.RespSchema,
 			}
 
 			if r.Schema != nil {
+				app.checkRules(r.Schema)
 				sch := c3po.ParseSchemaWithTag("braza", r.Schema)
 				r.MapCtrl[v].SchemaFielder = sch
 				r.MapCtrl[v].Schema = r.Schema
@@ -226,18 +287,22 @@ func (r *Route) compileMethods() {
 	if len(r.Methods) <= 1 {
 		r.Methods = []string{"GET", "HEAD"}
 		r.MapCtrl["GET"] = &Meth{
-			Func: r.Func,
+			Func:       r.Func,
+			RespSchema: r.RespSchema,
 		}
 	}
 }
 
-func (r *Route) parse() {
+func (r *Route) parse(app *App) {
 	if r.Func == nil && r.MapCtrl == nil {
 		l.err.Fatalf("Route '%s' need a Func or MapCtrl\n", r.Name)
 	}
 
-	r.compileUrl()
-	r.compileMethods()
+	r.compileUrl(app)
+	r.compileMethods(app)
+	if r.hub != nil && !slices.Contains(app.hubs, r.hub) {
+		app.hubs = append(app.hubs, r.hub)
+	}
 	if r.Cors != nil {
 		r.Cors.AllowMethods = r.Methods
 	} else {
@@ -270,35 +335,40 @@ func (r *Route) matchURL(ctx *Ctx, url string) bool {
 	nurl := strings.TrimPrefix(url, "/")
 	nurl = strings.TrimSuffix(nurl, "/")
 	urlSplit := strings.Split(nurl, "/")
+	if nurl == "" {
+		urlSplit = nil // all segments of route are optional
+	}
 
 	lSplit := len(urlSplit)
-	lRegex := len(r.urlRegex)
+	lSegs := len(r.segments)
 
-	if lSplit != lRegex {
+	if lSplit != lSegs {
 		if !ctx.App.DisableStatic && r.isStatic {
 			if strings.HasPrefix(ctx.Request.URL.Path, ctx.App.StaticUrlPath) {
 				return true
 			}
 		}
-		if r.isUrlPrefix {
-			if lRegex < lSplit {
-				for i, uRe := range r.urlRegex {
-					str := urlSplit[i]
-					if !uRe.MatchString(str) {
-						return false
-					}
-				}
-				return true
-			}
+		if lSplit < r.required || (lSplit > lSegs && !r.isUrlPrefix) {
+			return false
 		}
-		return false
 	}
 
-	for i, uRe := range r.urlRegex {
+	for i, seg := range r.segments {
+		if i >= lSplit || seg.isPath { // the {path:*} is not validated
+			break
+		}
 		str := urlSplit[i]
-		if !uRe.MatchString(str) {
+		if !seg.regex.MatchString(str) {
 			return false
 		}
+		if seg.isVar {
+			if _, _, err := seg.parse(str); err != nil {
+				return false
+			}
+		}
+	}
+	if lSplit == 0 {
+		return true
 	}
 
 	if ctx.App.StrictSlash {
@@ -328,53 +398,122 @@ func (r *Route) match(ctx *Ctx) bool {
 
 	if meth, ok := r.MapCtrl[m]; ok {
 		mi.MethodNotAllowed = nil
+		ctx.Response.header.Del("Allow")
 		if meth.Func != nil {
 			mi.Func = meth.Func
 		}
 		mi.Match = true
 		mi.Route = r
 		ctx.SchemaFielder = meth.SchemaFielder
+		ctx.respSchema = meth.RespSchema
 		return true
 	}
 
 	mi.Route = nil
+	mi.Router = r.router // the router of 405 error
 	mi.MethodNotAllowed = ErrorMethodMismatch
+
+	allow := slices.Clone(r.Methods)
+	slices.Sort(allow)
+	ctx.Response.header.Set("Allow", strings.Join(allow, ", "))
 	return false
 }
 
-func (r *Route) mountURI(args ...string) string {
-	var (
-		params = map[string]string{}
-	)
+/*
+example:
 
-	c := len(args)
-	for i := 0; i < c; i++ {
-		if i%2 != 0 {
+	route.Url = "/user/{id:int}/{path:*}"
+	requestUrl := "/user/123/foo/bar"
+	route.pathValues(requestUrl)
+	// map[string]string{"id":"123", "path":"/foo/bar"}
+	// map[string]any{"id":123, "path":"/foo/bar"}
+
+	route.Url = "/posts/{page:int=1}"
+	requestUrl := "/posts"
+	route.pathValues(requestUrl)
+	// map[string]string{"page":"1"}
+	// map[string]any{"page":1}
+*/
+func (r *Route) pathValues(urlReq string) (map[string]string, map[string]any) {
+	args := map[string]string{}
+	values := map[string]any{}
+	var req []string
+	if u := strings.Trim(urlReq, "/"); u != "" {
+		req = strings.Split(u, "/")
+	}
+
+	for i, seg := range r.segments {
+		if !seg.isVar {
+			continue
+		}
+		if i >= len(req) { // omitted optional segment
+			if seg.defValue != nil {
+				args[seg.name] = seg.def
+				values[seg.name] = seg.defValue
+			}
 			continue
 		}
-		params[args[i]] = args[i+1]
+		if seg.isPath { // if /{filepath:*} || /{filepath:path}
+			str := "/" + strings.Join(req[i:], "/")
+			if strings.HasSuffix(urlReq, "/") {
+				str += "/"
+			}
+			args[seg.name] = str
+			values[seg.name] = str
+			break
+		}
+		v, _, err := seg.parse(req[i])
+		if err != nil { // unreachable: the url was validated in the match
+			v = req[i]
+		}
+		args[seg.name] = req[i]
+		values[seg.name] = v
 	}
+	return args, values
+}
 
-	// Pre Build
-	var sUrl = strings.Split(r.Url, "/")
+// build the url of route. the params that aren't route variables are in query
+func (r *Route) mountURI(params map[string]string) string {
 	var urlBuf strings.Builder
+	var omitted []*urlSegment // optionals not passed, written only if a next segment is passed
 
 	// Build path
-	for _, str := range sUrl {
-		if re.isVar.MatchString(str) {
-			fname := re.getVarName(str)
-			value, ok := params[fname]
-			if !ok {
-				if !re.isVarOpt.MatchString(str) {
-					panic(fmt.Errorf("Route '%s' needs parameter '%s' but not passed", r.Name, str))
-				}
-			} else {
-				urlBuf.WriteString("/" + value)
-				delete(params, fname)
+	for _, seg := range r.segments {
+		if !seg.isVar {
+			urlBuf.WriteString("/" + seg.name)
+			continue
+		}
+		value, ok := params[seg.name]
+		if !ok {
+			if !seg.optional {
+				panic(fmt.Errorf("Route '%s' needs parameter '%s' but not passed", r.Name, seg.name))
 			}
+			omitted = append(omitted, seg)
+			continue
+		}
+		for _, o := range omitted {
+			if o.defValue == nil {
+				panic(fmt.Errorf("Route '%s' needs parameter '%s' to build the segment '%s'", r.Name, o.name, seg.name))
+			}
+			urlBuf.WriteString("/" + strings.TrimPrefix(o.def, "/"))
+		}
+		omitted = nil
+		if seg.isPath {
+			urlBuf.WriteString("/" + strings.TrimPrefix(value, "/"))
 		} else {
+			if !seg.regex.MatchString(value) {
+				panic(fmt.Errorf("Route '%s': invalid value of parameter '%s': '%s'", r.Name, seg.name, value))
+			}
+			_, str, err := seg.parse(value)
+			if err != nil {
+				panic(fmt.Errorf("Route '%s': invalid value of parameter '%s': %v", r.Name, seg.name, err))
+			}
 			urlBuf.WriteString("/" + str)
 		}
+		delete(params, seg.name)
+	}
+	if urlBuf.Len() == 0 || (r.hasSufix && !strings.HasSuffix(urlBuf.String(), "/")) {
+		urlBuf.WriteString("/")
 	}
 	// Build Query
 	var query strings.Builder
@@ -491,3 +630,49 @@ func Handler(h http.Handler) Func {
 	}
 	return func(ctx *Ctx) { h.ServeHTTP(ctx, ctx.Request.raw) }
 }
+
+/*
+Use a net/http middleware (func(http.Handler) http.Handler) in braza
+
+	app.Middlewares = []braza.Func{
+		braza.WrapMiddleware(otelhttp.NewMiddleware("api")),
+		braza.WrapMiddleware(handlers.CompressHandler),
+	}
+
+the status, headers and body written by braza pass through the writer of middleware
+and the changes in *http.Request (context, headers...) are visible in ctx.Request.
+if the middleware doesn't call the next handler, the chain is stopped
+*/
+func WrapMiddleware(mw func(http.Handler) http.Handler) Func {
+	if mw == nil {
+		panic("middleware is nil")
+	}
+	return func(ctx *Ctx) {
+		called := false
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			if r != ctx.Request.raw {
+				ctx.Request.setRaw(r)
+			}
+			ctx.Next()
+			if rw, ok := w.(*Ctx); ok && rw == ctx {
+				return
+			}
+			// the middleware wrapped the writer: replay the response through it
+			rsp := ctx.Response
+			body := bytes.Clone(rsp.Bytes())
+			rsp.Reset()
+			w.WriteHeader(rsp.StatusCode)
+			w.Write(body)
+		})
+
+		rq := ctx.Request
+		if rq.bodyWasRead && !strings.HasPrefix(rq.ContentType, "multipart/") {
+			rq.raw.Body = io.NopCloser(bytes.NewReader(rq.Body.Bytes())) // the body was read by braza
+		}
+		mw(next).ServeHTTP(ctx, rq.raw)
+		if !called {
+			ctx.Abort()
+		}
+	}
+}
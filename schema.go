@@ -1,19 +1,20 @@
 package braza
 
 import (
-	"bytes"
-	"errors"
-	"fmt"
 	"reflect"
 	"strings"
 
 	"github.com/ethoDomingues/c3po"
 )
 
+/*
+Mount the schema with the values of request. The missing and invalid fields are returned in a
+SchemaError, with the schema partially mounted (the validation rules can still be checked)
+*/
 func MountSchemaFromRequest(f *c3po.Fielder, rq *Request) (any, error) {
 	var sch any
 	var err error
-	var errs = []error{}
+	var errs = SchemaError{}
 	switch f.Type {
 	default:
 		v, isFile := getData(f, rq)
@@ -23,7 +24,7 @@ func MountSchemaFromRequest(f *c3po.Fielder, rq *Request) (any, error) {
 		}
 		if v == nil || v == "" {
 			if f.Required {
-				return nil, c3po.RetMissing(f)
+				return nil, SchemaError{mountError(f, mountName(f), "required", nil)}
 			}
 			sch = reflect.New(schT).Elem()
 			break
@@ -37,7 +38,7 @@ func MountSchemaFromRequest(f *c3po.Fielder, rq *Request) (any, error) {
 		_sch := reflect.New(schT).Elem()
 		schV := reflect.ValueOf(v)
 		if !c3po.SetReflectValue(_sch, schV, f.Escape) {
-			return nil, c3po.RetInvalidType(f)
+			return nil, SchemaError{mountError(f, mountName(f), "type", nil)}
 		}
 		return f.CheckSchPtr(_sch), nil
 	case reflect.Slice:
@@ -45,7 +46,7 @@ func MountSchemaFromRequest(f *c3po.Fielder, rq *Request) (any, error) {
 		if v == nil {
 			sch = reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(f.SliceType.Schema)), 0, 0)
 			if f.Required {
-				return nil, c3po.RetMissing(f)
+				return nil, SchemaError{mountError(f, mountName(f), "required", nil)}
 			}
 			return sch.(reflect.Value).Interface(), nil
 		}
@@ -55,7 +56,7 @@ func MountSchemaFromRequest(f *c3po.Fielder, rq *Request) (any, error) {
 			sch, err = f.Decode(v)
 		}
 		if err != nil {
-			errs = append(errs, err)
+			errs = append(errs, mountError(f, mountName(f), "type", err))
 		}
 	case reflect.Struct:
 		var rt reflect.Type
@@ -65,15 +66,18 @@ func MountSchemaFromRequest(f *c3po.Fielder, rq *Request) (any, error) {
 			rt = reflect.TypeOf(f.Schema)
 		}
 		_sch := reflect.New(rt).Elem()
+		rq.absentFields = map[string]bool{}
 		for i := 0; i < rt.NumField(); i++ {
 			fName := f.FieldsByIndex[i]
 			fielder := f.Children[fName]
 			rtField := _sch.Field(i)
+			name := schemaFieldName(rt.Field(i)) // the same name of validation errors
 
 			v, isFile := getData(fielder, rq)
 			if v == nil {
+				rq.absentFields[name] = true
 				if fielder.Required {
-					errs = append(errs, c3po.RetMissing(fielder))
+					errs = append(errs, mountError(fielder, name, "required", nil))
 				}
 				continue
 			}
@@ -83,59 +87,58 @@ func MountSchemaFromRequest(f *c3po.Fielder, rq *Request) (any, error) {
 					if ok && len(_v) > 0 {
 						v = _v[0]
 					} else {
+						rq.absentFields[name] = true
 						if fielder.Required {
-							errs = append(errs, c3po.RetMissing(fielder))
+							errs = append(errs, mountError(fielder, name, "required", nil))
 						}
 						continue
 					}
 				}
 				if !c3po.SetReflectValue(rtField, reflect.ValueOf(v), false) {
-					errs = append(errs, c3po.RetInvalidType(fielder))
+					errs = append(errs, mountError(fielder, name, "type", nil))
 				}
 			} else {
 				schF, e := fielder.Decode(v)
 
 				if e != nil {
-					errs = append(errs, e)
+					errs = append(errs, mountError(fielder, name, "type", e))
 				} else {
 					if !c3po.SetReflectValue(rtField, reflect.ValueOf(schF), fielder.Escape) {
-						errs = append(errs, c3po.RetInvalidType(fielder))
+						errs = append(errs, mountError(fielder, name, "type", nil))
 					}
 				}
 			}
 		}
-		if len(errs) == 0 {
-			sch = f.CheckSchPtr(_sch)
-		}
+		sch = f.CheckSchPtr(_sch) // partial, if has errors
 	}
 	if len(errs) > 0 {
-		if f.Name != "" {
-			return sch, fmt.Errorf(`{"%s":%v}`, f.Name, formatErr(errs...))
-		}
-		return nil, formatErr(errs...)
+		return sch, errs
 	}
 	return sch, nil
 }
 
-func formatErr(errs ...error) error {
-	if len(errs) > 1 {
-		errBuf := bytes.NewBufferString("[")
-		for i, e := range errs {
-			if i > 0 {
-				errBuf.WriteString(",")
-			}
-			errBuf.WriteString(e.Error())
-		}
-		errBuf.WriteString("]")
-		return errors.New(errBuf.String())
+// the name of a schema that is not a struct
+func mountName(f *c3po.Fielder) string {
+	if f.Name != "" {
+		return f.Name
+	}
+	return f.RealName
+}
+
+// a error of mount in a FieldError, with the location of field ("in")
+func mountError(f *c3po.Fielder, name, rule string, err error) *FieldError {
+	in := strings.ToLower(f.Tags["in"])
+	if in == "" {
+		in = "body"
 	}
-	if len(errs) == 1 {
-		e := errs[0]
-		if e != nil {
-			return e
+	msg := "is required"
+	if rule == "type" {
+		msg = "has a invalid type"
+		if err != nil {
+			msg = err.Error()
 		}
 	}
-	return nil
+	return &FieldError{Field: name, In: in, Rule: rule, Message: msg}
 }
 
 // return nil if not exists and a bool if is a file
@@ -160,9 +163,9 @@ func getData(f *c3po.Fielder, rq *Request) (any, bool) {
 		if isFile {
 			return rq.Files[f.Name], true
 		}
-		return rq.Form[f.Name], false
+		return formValue(f, rq.Form[f.Name]), false
 	case "body":
-		return rq.Form[f.Name], false
+		return formValue(f, rq.Form[f.Name]), false
 	case "path":
 		return rq.PathArgs[f.Name], false
 	case "subdomain":
@@ -172,7 +175,7 @@ func getData(f *c3po.Fielder, rq *Request) (any, bool) {
 	case "headers":
 		return rq.Header.Get(f.Name), false
 	case "query":
-		return rq.Query.Get(f.Name), false
+		return formValue(f, rq.QueryValues[f.Name]), false
 	case "auth":
 		if u, p, ok := rq.BasicAuth(); ok {
 			if f.Name == "username" {
@@ -184,3 +187,21 @@ func getData(f *c3po.Fielder, rq *Request) (any, bool) {
 	}
 	return nil, false
 }
+
+// the repeated keys of query and form are lists: a single value in a slice field is a list
+// of one item, and a list in a non-slice field uses the first value
+func formValue(f *c3po.Fielder, v any) any {
+	switch _v := v.(type) {
+	case string:
+		if f.Type == reflect.Slice {
+			return []any{_v}
+		}
+	case []any:
+		if f.Type != reflect.Slice && len(_v) > 0 {
+			if s, ok := _v[0].(string); ok {
+				return s
+			}
+		}
+	}
+	return v
+}
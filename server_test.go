@@ -0,0 +1,64 @@
+package braza
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"golang.org/x/net/http2"
+)
+
+func TestH2C(t *testing.T) {
+	app := newTestApp(t, &Config{
+		Env:                   "production",
+		Silent:                true,
+		EnableH2C:             true,
+		DisableGracefulReload: true,
+	}, GET("/", func(ctx *Ctx) { ctx.TEXT(ctx.Request.ProtoMajor, 200) }))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveTestApp(t, app, ln)
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true, // prior knowledge, without TLS
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+	resp, err := client.Get("http://" + ln.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.ProtoMajor != 2 || string(body) != "2" {
+		t.Fatalf("not served in http/2: proto %s, body %q", resp.Proto, body)
+	}
+}
+
+func TestHTTP1WithoutH2C(t *testing.T) {
+	app := newTestApp(t, &Config{Env: "production", Silent: true, DisableGracefulReload: true},
+		GET("/", func(ctx *Ctx) { ctx.TEXT(ctx.Request.ProtoMajor, 200) }))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveTestApp(t, app, ln)
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.ProtoMajor != 1 {
+		t.Fatalf("proto: got %s, want HTTP/1.1", resp.Proto)
+	}
+	if resp.Header.Get("Alt-Svc") != "" {
+		t.Error("the Alt-Svc header must be sent only with HTTP/3")
+	}
+}
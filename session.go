@@ -19,15 +19,18 @@ type Session struct {
 
 // validate a cookie session
 func (s *Session) validate(c *http.Cookie, ctx *Ctx) {
-	secret := ctx.App.SecretKey
-	pubKey := ctx.App.SessionPublicKey
-	privKey := ctx.App.SessionPrivateKey
+	secret, pubKey, privKey := ctx.sessionKeys()
 
 	s.claims = jwt.MapClaims{}
 	if secret == "" && pubKey == nil && privKey == nil {
 		return
 	}
-	tkn, err := jwt.Parse(c.Value, func(t *jwt.Token) (interface{}, error) { return pubKey, nil })
+	tkn, err := jwt.Parse(c.Value, func(t *jwt.Token) (interface{}, error) {
+		if pubKey != nil && privKey != nil {
+			return pubKey, nil
+		}
+		return []byte(secret), nil
+	})
 	if err != nil {
 		return
 	}
@@ -74,9 +77,7 @@ func (s *Session) Del(key string) string {
 
 // Returns a cookie, with the value being a jwt
 func (s *Session) save(ctx *Ctx) *http.Cookie {
-	secret := ctx.App.SecretKey
-	pubKey := ctx.App.SessionPublicKey
-	privKey := ctx.App.SessionPrivateKey
+	secret, pubKey, privKey := ctx.sessionKeys()
 	if secret == "" && pubKey == nil && privKey == nil {
 		l.warn.Println("to use the session you need to set a 'App.Secret' or a 'public/private key'. rejecting session")
 		return nil
@@ -126,15 +127,12 @@ func (s *Session) save(ctx *Ctx) *http.Cookie {
 
 // Returns a JWT Token from session data
 func (s *Session) GetSign(ctx *Ctx) (string, error) {
-	secret := ctx.App.SecretKey
-	pubKey := ctx.App.SessionPublicKey
-	privKey := ctx.App.SessionPrivateKey
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, s.claims)
+	secret, pubKey, privKey := ctx.sessionKeys()
 	if secret == "" && pubKey == nil && privKey == nil {
 		return "", errors.New("to set a session value, you need a set a 'App.Secret' or a 'public/private key'")
 	}
 	if pubKey != nil && privKey != nil {
-		return token.SignedString(privKey)
+		return jwt.NewWithClaims(jwt.SigningMethodRS256, s.claims).SignedString(privKey)
 	}
-	return token.SignedString([]byte(secret))
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, s.claims).SignedString([]byte(secret))
 }
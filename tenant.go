@@ -0,0 +1,183 @@
+package braza
+
+import (
+	"crypto/rsa"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+/*
+A tenant of app (a customer, a shop, a organization...). The empty fields use the values of app
+
+	app.TenantResolver = braza.TenantFromPathArg("tenant", func(id string) (*braza.Tenant, error) {
+		shop, err := db.FindShop(id)
+		if err != nil || shop == nil {
+			return nil, err // nil tenant -> 404
+		}
+		return &braza.Tenant{
+			ID:             shop.ID,
+			SecretKey:      shop.SecretKey,
+			TemplateFolder: "templates/" + shop.ID,
+			UrlArgs:        map[string]string{"tenant": shop.ID},
+			Data:           shop,
+		}, nil
+	})
+
+	shops := braza.NewRouter("shops")
+	shops.Subdomain = "{tenant}"
+	shops.GET("/", func(ctx *braza.Ctx) {
+		shop := ctx.Tenant.Data.(*Shop)
+		ctx.RenderTemplate("index.html", shop) // templates/{shop.ID}/index.html or templates/index.html
+	})
+*/
+type Tenant struct {
+	ID string
+
+	// host of tenant, used in UrlFor (ex: custom domains 'shop.com.br').
+	// if empty, the host of router is built with the UrlArgs
+	Host string
+
+	// values of url and host variables of tenant, used in UrlFor
+	//	map[string]string{"tenant": "acme"} // "{tenant}.example.com" -> "acme.example.com"
+	UrlArgs map[string]string
+
+	SecretKey         string // for sign session and cookies of tenant
+	SessionPublicKey  *rsa.PublicKey
+	SessionPrivateKey *rsa.PrivateKey
+
+	TemplateFolder string // the templates of this folder override the templates of app
+	StaticFolder   string // the files of this folder override the static files of app
+
+	Data any // custom data of tenant
+}
+
+/*
+returns the tenant of request:
+  - nil, nil: the request doesn't have a tenant (uses the settings of app)
+  - nil, ErrorNotFound: unknown tenant (404)
+  - nil, err: 500
+*/
+type TenantResolver func(ctx *Ctx) (*Tenant, error)
+
+// lookup of tenants. returns nil if the tenant does not exist
+type TenantLookup func(key string) (*Tenant, error)
+
+func lookupTenant(key string, lookup TenantLookup) (*Tenant, error) {
+	if key == "" {
+		return nil, nil
+	}
+	t, err := lookup(key)
+	if err == nil && t == nil {
+		return nil, ErrorNotFound
+	}
+	return t, err
+}
+
+/*
+Resolve the tenant by host of request (without port). for custom domains
+
+	app.TenantResolver = braza.TenantFromHost(func(host string) (*braza.Tenant, error) {
+		return tenantsByDomain[host], nil
+	})
+*/
+func TenantFromHost(lookup TenantLookup) TenantResolver {
+	return func(ctx *Ctx) (*Tenant, error) {
+		return lookupTenant(normalizeHost(ctx.Request.Host), lookup)
+	}
+}
+
+/*
+Resolve the tenant by header of request
+
+	app.TenantResolver = braza.TenantFromHeader("X-Tenant-ID", lookup)
+*/
+func TenantFromHeader(header string, lookup TenantLookup) TenantResolver {
+	return func(ctx *Ctx) (*Tenant, error) {
+		return lookupTenant(ctx.Request.Header.Get(header), lookup)
+	}
+}
+
+/*
+Resolve the tenant by a variable of url or host
+
+	router.Subdomain = "{tenant}" // or router.Prefix = "/t/{tenant}"
+	app.TenantResolver = braza.TenantFromPathArg("tenant", lookup)
+*/
+func TenantFromPathArg(name string, lookup TenantLookup) TenantResolver {
+	return func(ctx *Ctx) (*Tenant, error) {
+		return lookupTenant(ctx.Request.PathArgs[name], lookup)
+	}
+}
+
+// exec the TenantResolver of app, after the match of route
+func (ctx *Ctx) resolveTenant() {
+	if ctx.App.TenantResolver == nil {
+		return
+	}
+	t, err := ctx.App.TenantResolver(ctx)
+	if err != nil {
+		if errors.Is(err, ErrorNotFound) {
+			ctx.NotFound()
+		}
+		l.err.Println("tenant:", err)
+		ctx.InternalServerError()
+	}
+	ctx.Tenant = t
+}
+
+func (ctx *Ctx) tenantID() string {
+	if ctx.Tenant == nil {
+		return ""
+	}
+	return ctx.Tenant.ID
+}
+
+// the keys of session (of tenant or app)
+func (ctx *Ctx) sessionKeys() (string, *rsa.PublicKey, *rsa.PrivateKey) {
+	if t := ctx.Tenant; t != nil && (t.SecretKey != "" || t.SessionPrivateKey != nil) {
+		return t.SecretKey, t.SessionPublicKey, t.SessionPrivateKey
+	}
+	return ctx.App.SecretKey, ctx.App.SessionPublicKey, ctx.App.SessionPrivateKey
+}
+
+// returns the file of tenant folder (if exists), else the file of app folder
+func (ctx *Ctx) tenantFile(tenantFolder, appFolder, name string) string {
+	name = filepath.Clean("/" + name) // avoid '../'
+	if ctx.Tenant != nil && tenantFolder != "" {
+		p := filepath.Join(tenantFolder, name)
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() {
+			return p
+		}
+	}
+	return filepath.Join(appFolder, name)
+}
+
+func (ctx *Ctx) templateFile(tmpl string) string {
+	var folder string
+	if ctx.Tenant != nil {
+		folder = ctx.Tenant.TemplateFolder
+	}
+	return ctx.tenantFile(folder, ctx.App.TemplateFolder, tmpl)
+}
+
+func (ctx *Ctx) staticFile(name string) string {
+	var folder string
+	if ctx.Tenant != nil {
+		folder = ctx.Tenant.StaticFolder
+	}
+	return ctx.tenantFile(folder, ctx.App.StaticFolder, name)
+}
+
+/*
+Url Builder for a tenant: uses the UrlArgs and the Host of tenant
+
+	t := &braza.Tenant{ID: "acme", UrlArgs: map[string]string{"tenant": "acme"}}
+	app.TenantUrlFor(t, "shops.index", true) // http://acme.example.com/
+
+	t := &braza.Tenant{ID: "acme", Host: "acme.com.br"}
+	app.TenantUrlFor(t, "shops.index", true) // http://acme.com.br/
+*/
+func (app *App) TenantUrlFor(t *Tenant, name string, external bool, args ...string) string {
+	return app.urlFor(t, name, external, args...)
+}
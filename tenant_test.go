@@ -0,0 +1,72 @@
+package braza
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTenantResolver(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "acme"), 0o755)
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte("app page"), 0o644)
+	os.WriteFile(filepath.Join(dir, "acme", "index.html"), []byte("acme page"), 0o644)
+
+	tenants := map[string]*Tenant{
+		"acme":  {ID: "acme", TemplateFolder: filepath.Join(dir, "acme"), UrlArgs: map[string]string{"tenant": "acme"}},
+		"globe": {ID: "globe"},
+	}
+	page := &Route{Url: "/t/{tenant}/", Name: "index", Func: func(ctx *Ctx) { ctx.RenderTemplate("index.html") }}
+	app := NewApp(&Config{DisableStatic: true, TemplateFolder: dir})
+	app.AddRoute(page)
+	app.TenantResolver = TenantFromPathArg("tenant", func(id string) (*Tenant, error) {
+		if id == "broken" {
+			return nil, errors.New("db is down")
+		}
+		return tenants[id], nil
+	})
+	app.Build()
+
+	cases := []struct {
+		url  string
+		code int
+		body string
+	}{
+		{"/t/acme/", 200, "acme page"},
+		{"/t/globe/", 200, "app page"}, // without the template of tenant
+		{"/t/unknown/", 404, ""},
+		{"/t/broken/", 500, ""},
+	}
+	for _, c := range cases {
+		rec := doRequest(app, "GET", c.url, "")
+		if rec.Code != c.code || (c.code == 200 && rec.Body.String() != c.body) {
+			t.Errorf("%s: got %d %q, want %d %q", c.url, rec.Code, rec.Body.String(), c.code, c.body)
+		}
+	}
+
+	if url := app.TenantUrlFor(tenants["acme"], "index", false); url != "/t/acme/" {
+		t.Errorf("TenantUrlFor: %s", url)
+	}
+}
+
+func TestTenantFromHeader(t *testing.T) {
+	app := NewApp(&Config{DisableStatic: true})
+	app.GET("/", func(ctx *Ctx) {
+		if ctx.Tenant == nil {
+			ctx.TEXT("no tenant", 200)
+		}
+		ctx.TEXT(ctx.Tenant.ID, 200)
+	})
+	app.TenantResolver = TenantFromHeader("X-Tenant-ID", func(id string) (*Tenant, error) {
+		return &Tenant{ID: id}, nil
+	})
+	app.Build()
+
+	if body := doRequest(app, "GET", "/", "", "X-Tenant-ID", "acme").Body.String(); body != "acme" {
+		t.Errorf("tenant: %q", body)
+	}
+	if body := doRequest(app, "GET", "/", "").Body.String(); body != "no tenant" {
+		t.Errorf("without the header: %q", body)
+	}
+}
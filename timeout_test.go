@@ -0,0 +1,62 @@
+package braza
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRouteTimeout(t *testing.T) {
+	slow := GET("/slow", func(ctx *Ctx) {
+		select {
+		case <-ctx.Done():
+			ctx.CheckErr(ctx.Err())
+		case <-time.After(time.Second):
+			ctx.TEXT("too late", 200)
+		}
+	})
+	slow.Timeout = time.Millisecond * 20
+	bug := GET("/bug", func(ctx *Ctx) {
+		<-ctx.Done()
+		panic("a real bug")
+	})
+	bug.Name = "bug"
+	bug.Timeout = time.Millisecond * 20
+	fast := GET("/fast", func(ctx *Ctx) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("the context must have a deadline")
+		}
+		ctx.TEXT("ok", 200)
+	})
+	fast.Name = "fast"
+	fast.Timeout = time.Second
+
+	app := newTestApp(t, nil, slow, bug, fast)
+	app.ErrorHandler(503, func(ctx *Ctx) { ctx.TEXT("timeout", 503) })
+
+	rec := doRequest(app, "GET", "/slow", "")
+	expectStatus(t, rec, http.StatusServiceUnavailable)
+	if rec.Body.String() != "timeout" {
+		t.Errorf("the error handler of 503 was not used: %s", rec.Body.String())
+	}
+	expectStatus(t, doRequest(app, "GET", "/bug", ""), http.StatusInternalServerError)
+	expectStatus(t, doRequest(app, "GET", "/fast", ""), http.StatusOK)
+}
+
+func TestCtxIsRequestContext(t *testing.T) {
+	type key struct{}
+	app := newTestApp(t, nil, GET("/", func(ctx *Ctx) {
+		var c context.Context = ctx
+		if c.Value(key{}) != "v" {
+			t.Error("the values of request context must be in ctx")
+		}
+		ctx.TEXT("ok", 200)
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), key{}, "v"))
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	expectStatus(t, rec, http.StatusOK)
+}
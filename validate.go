@@ -0,0 +1,431 @@
+package braza
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+/*
+Validation rules in the braza tag of schemas. All the failures are collected and
+returned in a SchemaError (400 Bad Request)
+
+	type Schema struct {
+		Name   string `braza:"in=body,required,min=3,max=50"`
+		Email  string `braza:"in=body,email"`
+		Site   string `braza:"in=body,url"`
+		ID     string `braza:"in=path,uuid"`
+		Code   string `braza:"in=query,len=6,regex=^[A-Z]{1,3}[0-9]+$"` // regex is the last rule: it takes the rest of tag
+		Kind   string `braza:"in=query,oneof=a|b|c"` // or enum=a|b|c
+		Limit  int    `braza:"in=query,min=1,max=100"`
+		Start  int    `braza:"in=query"`
+		End    int    `braza:"in=query,gt=Start"` // gt, gte, lt, lte: compares with other field
+		Doc    string `braza:"in=body,cpf"`       // custom validator, see App.RegisterValidator
+	}
+
+	min, max: value of numbers, length of strings and slices
+	len: length of strings and slices
+
+the rules are applied on all the values sent, "" and 0 too. the absent fields of request and
+the nil pointers, slices and maps are not checked (use 'required'). in App.Validate, only the
+nil values are absent: use pointers for the optional fields
+*/
+type Validator func(value any, param string) error
+
+// a validation failure of a field
+type FieldError struct {
+	Field   string `json:"field"`
+	In      string `json:"in"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (e *FieldError) Error() string { return e.Field + ": " + e.Message }
+
+// the validation failures of a schema. Error() returns a json array
+type SchemaError []*FieldError
+
+func (e SchemaError) Error() string {
+	b, _ := json.Marshal(e)
+	return string(b)
+}
+
+var builtinRules = map[string]bool{
+	"min": true, "max": true, "len": true, "regex": true, "oneof": true, "enum": true,
+	"email": true, "url": true, "uuid": true, "gt": true, "gte": true, "lt": true, "lte": true,
+}
+
+// keys of tag that are not rules
+var tagOptions = map[string]bool{
+	"in": true, "name": true, "required": true, "escape": true, "default": true, "omitempty": true,
+}
+
+/*
+Register a custom validation rule, used in the braza tag by name
+
+	app.RegisterValidator("cpf", func(v any, param string) error {
+		if !isCPF(v.(string)) {
+			return errors.New("invalid cpf")
+		}
+		return nil
+	})
+
+	type Schema struct {
+		Doc string `braza:"in=body,cpf"`
+		Age int    `braza:"in=body,older=18"` // param: "18"
+	}
+*/
+func (app *App) RegisterValidator(name string, f Validator) {
+	if builtinRules[name] || tagOptions[name] {
+		panic(fmt.Errorf("validator '%s' is a builtin rule", name))
+	}
+	if app.validators == nil {
+		app.validators = map[string]Validator{}
+	}
+	app.validators[name] = f
+}
+
+type rule struct {
+	name  string
+	param string
+	re    *regexp.Regexp
+}
+
+type fieldRules struct {
+	index  int
+	name   string
+	in     string
+	rules  []*rule
+	nested bool // struct, pointer to struct or slice of structs
+}
+
+var schemaRules sync.Map // reflect.Type -> []*fieldRules
+
+// the name of field in the errors: the 'name' of braza tag, or the name of field
+func schemaFieldName(sf reflect.StructField) string {
+	for _, opt := range strings.Split(sf.Tag.Get("braza"), ",") {
+		if k, v, _ := strings.Cut(strings.TrimSpace(opt), "="); k == "name" && v != "" {
+			return v
+		}
+	}
+	return sf.Name
+}
+
+func parseRules(t reflect.Type) []*fieldRules {
+	if v, ok := schemaRules.Load(t); ok {
+		return v.([]*fieldRules)
+	}
+	fields := []*fieldRules{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		fr := &fieldRules{index: i, name: schemaFieldName(sf), in: "body"}
+		tag := sf.Tag.Get("braza")
+		if tag == "-" {
+			continue
+		}
+		opts := strings.Split(tag, ",")
+		for i, opt := range opts {
+			k, v, _ := strings.Cut(strings.TrimSpace(opt), "=")
+			if k == "regex" {
+				v = strings.Join(append([]string{v}, opts[i+1:]...), ",") // the rest of tag: "regex=^\d{1,3}$"
+			}
+			switch {
+			case k == "":
+			case k == "in":
+				fr.in = v
+			case k == "name":
+			case tagOptions[k]:
+			default:
+				r := &rule{name: k, param: v}
+				if k == "regex" {
+					r.re = regexp.MustCompile(v)
+				}
+				fr.rules = append(fr.rules, r)
+			}
+			if k == "regex" {
+				break
+			}
+		}
+		ft := sf.Type
+		for ft.Kind() == reflect.Pointer || ft.Kind() == reflect.Slice {
+			ft = ft.Elem()
+		}
+		fr.nested = ft.Kind() == reflect.Struct && ft != reflect.TypeOf(time.Time{}) && ft != reflect.TypeOf(File{})
+		if len(fr.rules) > 0 || fr.nested {
+			fields = append(fields, fr)
+		}
+	}
+	schemaRules.Store(t, fields)
+	return fields
+}
+
+// panics if the schema has a unknown rule (on route parse)
+func (app *App) checkRules(schema any) {
+	app.checkTypeRules(reflect.TypeOf(schema), map[reflect.Type]bool{})
+}
+
+// 'seen': the recursive schemas (ex: Children []Category) are checked only once
+func (app *App) checkTypeRules(t reflect.Type, seen map[reflect.Type]bool) {
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct || seen[t] {
+		return
+	}
+	seen[t] = true
+	for _, fr := range parseRules(t) {
+		for _, r := range fr.rules {
+			if _, ok := app.validators[r.name]; !ok && !builtinRules[r.name] {
+				panic(fmt.Errorf("schema '%s' has a unknown validation rule: '%s'", t.Name(), r.name))
+			}
+			switch r.name {
+			case "min", "max":
+				if _, err := strconv.ParseFloat(r.param, 64); err != nil {
+					panic(fmt.Errorf("schema '%s': rule '%s' needs a number: '%s'", t.Name(), r.name, r.param))
+				}
+			case "len":
+				if _, err := strconv.Atoi(r.param); err != nil {
+					panic(fmt.Errorf("schema '%s': rule 'len' needs a integer: '%s'", t.Name(), r.param))
+				}
+			case "gt", "gte", "lt", "lte":
+				if _, ok := t.FieldByName(r.param); !ok {
+					panic(fmt.Errorf("schema '%s': rule '%s', the field '%s' does not exist", t.Name(), r.name, r.param))
+				}
+			}
+		}
+		if fr.nested {
+			app.checkTypeRules(t.Field(fr.index).Type, seen)
+		}
+	}
+}
+
+/*
+Validate a struct with the rules of braza tag. Returns nil or a SchemaError
+
+	if err := app.Validate(user); err != nil {
+		ctx.JSON(err, 400)
+	}
+*/
+func (app *App) Validate(v any) error {
+	return app.validate(v, nil)
+}
+
+// 'absent': the fields of schema that are not in the request (by name)
+func (app *App) validate(v any, absent map[string]bool) error {
+	errs := SchemaError{}
+	app.validateValue(reflect.ValueOf(v), "", absent, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (app *App) validateValue(v reflect.Value, prefix string, absent map[string]bool, errs *SchemaError) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	for _, fr := range parseRules(v.Type()) {
+		fv := v.Field(fr.index)
+		name := prefix + fr.name
+		if absent[fr.name] || isNilValue(fv) {
+			continue
+		}
+		for _, r := range fr.rules {
+			if msg := app.checkRule(r, fv, v); msg != "" {
+				*errs = append(*errs, &FieldError{Field: name, In: fr.in, Rule: r.name, Message: msg})
+			}
+		}
+		if !fr.nested {
+			continue
+		}
+		if fv.Kind() == reflect.Slice {
+			for i := 0; i < fv.Len(); i++ {
+				app.validateValue(fv.Index(i), fmt.Sprintf("%s[%d].", name, i), nil, errs)
+			}
+		} else {
+			app.validateValue(fv, name+".", nil, errs)
+		}
+	}
+}
+
+// returns the error message, or "" if the value is valid
+func (app *App) checkRule(r *rule, v, parent reflect.Value) string {
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	switch r.name {
+	case "min", "max":
+		n, err := strconv.ParseFloat(r.param, 64)
+		if err != nil {
+			panic(fmt.Errorf("rule '%s' needs a number: '%s'", r.name, r.param))
+		}
+		size, isLen := valueSize(v)
+		if (r.name == "min" && size < n) || (r.name == "max" && size > n) {
+			limit := "at least"
+			if r.name == "max" {
+				limit = "at most"
+			}
+			if isLen {
+				return fmt.Sprintf("must have %s %s %s", limit, r.param, lenUnit(v))
+			}
+			return fmt.Sprintf("must be %s %s", limit, r.param)
+		}
+	case "len":
+		n, err := strconv.Atoi(r.param)
+		if err != nil {
+			panic(fmt.Errorf("rule 'len' needs a number: '%s'", r.param))
+		}
+		if size, _ := valueSize(v); int(size) != n {
+			return fmt.Sprintf("must have exactly %d %s", n, lenUnit(v))
+		}
+	case "regex":
+		if !r.re.MatchString(fmt.Sprint(v.Interface())) {
+			return "must match " + r.param
+		}
+	case "oneof", "enum":
+		str := fmt.Sprint(v.Interface())
+		for _, opt := range strings.Split(r.param, "|") {
+			if str == opt {
+				return ""
+			}
+		}
+		return "must be one of " + strings.ReplaceAll(r.param, "|", ", ")
+	case "email":
+		str := fmt.Sprint(v.Interface())
+		if a, err := mail.ParseAddress(str); err != nil || a.Address != str {
+			return "must be a valid email"
+		}
+	case "url":
+		u, err := url.Parse(fmt.Sprint(v.Interface()))
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return "must be a valid url"
+		}
+	case "uuid":
+		if _, err := uuid.Parse(fmt.Sprint(v.Interface())); err != nil {
+			return "must be a valid uuid"
+		}
+	case "gt", "gte", "lt", "lte":
+		other := parent.FieldByName(r.param)
+		if !other.IsValid() {
+			panic(fmt.Errorf("rule '%s': the field '%s' does not exist", r.name, r.param))
+		}
+		c, ok := compareValues(v, other)
+		if !ok {
+			return "can't be compared with " + r.param
+		}
+		valid := map[string]bool{"gt": c > 0, "gte": c >= 0, "lt": c < 0, "lte": c <= 0}[r.name]
+		if !valid {
+			op := map[string]string{"gt": "greater than", "gte": "greater than or equal to", "lt": "less than", "lte": "less than or equal to"}[r.name]
+			return "must be " + op + " " + r.param
+		}
+	default:
+		f, ok := app.validators[r.name]
+		if !ok {
+			panic(fmt.Errorf("unknown validation rule: '%s'", r.name))
+		}
+		if err := f(v.Interface(), r.param); err != nil {
+			return err.Error()
+		}
+	}
+	return ""
+}
+
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+		return v.IsNil()
+	}
+	return false
+}
+
+func lenUnit(v reflect.Value) string {
+	if v.Kind() == reflect.String {
+		return "characters"
+	}
+	return "items"
+}
+
+// the value of numbers, or the length of strings, slices and maps
+func valueSize(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false
+	}
+	return 0, false
+}
+
+func isNumber(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// -1, 0, 1 (numbers, strings and time.Time)
+func compareValues(a, b reflect.Value) (int, bool) {
+	for b.Kind() == reflect.Pointer {
+		if b.IsNil() {
+			return 0, false
+		}
+		b = b.Elem()
+	}
+	if ta, ok := a.Interface().(time.Time); ok {
+		tb, ok := b.Interface().(time.Time)
+		return ta.Compare(tb), ok
+	}
+	if a.Kind() == reflect.String && b.Kind() == reflect.String {
+		return strings.Compare(a.String(), b.String()), true
+	}
+	if !isNumber(a) || !isNumber(b) {
+		return 0, false
+	}
+	na, _ := valueSize(a)
+	nb, _ := valueSize(b)
+	switch {
+	case na < nb:
+		return -1, true
+	case na > nb:
+		return 1, true
+	}
+	return 0, true
+}
+
+// the schema errors in the 'errors' member of problem
+func schemaErrors(err error) (any, bool) {
+	var se SchemaError
+	if errors.As(err, &se) {
+		return se, true
+	}
+	return nil, false
+}
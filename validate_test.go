@@ -0,0 +1,144 @@
+package braza
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethoDomingues/c3po"
+)
+
+type testValidate struct {
+	Name  string  `braza:"in=body,min=3,max=10"`
+	Limit int     `braza:"in=query,min=1,max=100"`
+	Code  string  `braza:"in=query,len=4,regex=^[A-Z]{1,3}[0-9]+$"`
+	Kind  *string `braza:"in=query,oneof=a|b"`
+	Start int     `braza:"in=query"`
+	End   int     `braza:"in=query,gte=Start"`
+	Email string  `braza:"in=body,name=mail,email"`
+}
+
+func fieldErrors(err error) map[string]string {
+	m := map[string]string{}
+	var se SchemaError
+	if errors.As(err, &se) {
+		for _, fe := range se {
+			m[fe.Field] = fe.Rule
+		}
+	}
+	return m
+}
+
+func TestValidateRules(t *testing.T) {
+	app := NewApp(nil)
+	valid := testValidate{Name: "joe", Limit: 10, Code: "AB12", Start: 1, End: 2, Email: "joe@mail.com"}
+	if err := app.Validate(&valid); err != nil {
+		t.Fatalf("valid schema: %v", err)
+	}
+
+	invalid := testValidate{Name: "", Limit: 0, Code: "ABCD", Start: 5, End: 2, Email: "joe"}
+	got := fieldErrors(app.Validate(&invalid))
+	want := map[string]string{"Name": "min", "Limit": "min", "Code": "regex", "End": "gte", "mail": "email"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("errors: got %v, want %v", got, want)
+	}
+}
+
+func TestValidateAbsentFields(t *testing.T) {
+	app := NewApp(nil)
+	v := testValidate{Name: "joe", Code: "AB12", Email: "joe@mail.com"}
+	// Limit was not sent, Kind is nil
+	if err := app.validate(&v, map[string]bool{"Limit": true}); err != nil {
+		t.Fatalf("absent fields must not be checked: %v", err)
+	}
+	kind := "c"
+	v.Kind = &kind
+	if got := fieldErrors(app.validate(&v, map[string]bool{"Limit": true})); got["Kind"] != "oneof" {
+		t.Errorf("errors: %v", got)
+	}
+}
+
+func TestValidateCustomRule(t *testing.T) {
+	app := NewApp(nil)
+	app.RegisterValidator("even", func(v any, param string) error {
+		if v.(int)%2 != 0 {
+			return errors.New("must be even")
+		}
+		return nil
+	})
+	type sch struct {
+		N int `braza:"even"`
+	}
+	app.checkRules(&sch{})
+	if got := fieldErrors(app.Validate(&sch{N: 3})); got["N"] != "even" {
+		t.Errorf("errors: %v", got)
+	}
+}
+
+type testCategory struct {
+	Name     string `braza:"min=2"`
+	Children []testCategory
+	Parent   *testCategory
+}
+
+func TestCheckRulesRecursiveSchema(t *testing.T) {
+	app := NewApp(nil)
+	app.checkRules(&testCategory{}) // must not overflow the stack
+	c := &testCategory{Name: "ab", Children: []testCategory{{Name: "x"}}}
+	if got := fieldErrors(app.Validate(c)); got["Children[0].Name"] != "min" {
+		t.Errorf("errors: %v", got)
+	}
+}
+
+func TestCheckRulesInvalidParams(t *testing.T) {
+	cases := map[string]any{
+		"unknown rule": &struct {
+			A string `braza:"nope"`
+		}{},
+		"len float": &struct {
+			A string `braza:"len=2.5"`
+		}{},
+		"min text": &struct {
+			A int `braza:"min=a"`
+		}{},
+		"gt field": &struct {
+			A int `braza:"gt=B"`
+		}{},
+	}
+	for name, sch := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: must panic on route parse", name)
+				}
+			}()
+			NewApp(nil).checkRules(sch)
+		}()
+	}
+}
+
+func TestMountErrorsUseSchemaNames(t *testing.T) {
+	type sch struct {
+		Name string `braza:"in=body,name=full_name,required"`
+		ID   string `braza:"in=query,required"`
+	}
+	app := newTestApp(t, nil, POST("/users", func(ctx *Ctx) { ctx.TEXT("ok", 200) }))
+	app.BeforeRequest = func(ctx *Ctx) {
+		ctx.SchemaFielder = &c3po.Fielder{Type: reflect.Struct, Schema: sch{},
+			FieldsByIndex: map[int]string{0: "Name", 1: "ID"},
+			Children: map[string]*c3po.Fielder{
+				"Name": {Name: "fullName", Required: true, Tags: map[string]string{"in": "body"}},
+				"ID":   {Name: "ID", Required: true, Tags: map[string]string{"in": "query"}},
+			}}
+	}
+	rec := doRequest(app, "POST", "/users", `{}`, "Content-Type", "application/json")
+	expectStatus(t, rec, http.StatusBadRequest)
+	body := rec.Body.String()
+	for _, want := range []string{`"field":"full_name","in":"body","rule":"required"`, `"field":"ID","in":"query","rule":"required"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body %s: without %s", body, want)
+		}
+	}
+}
@@ -6,68 +6,288 @@ import (
 	"io/fs"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+
+	"github.com/ethoDomingues/c3po"
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	envSupervised = "BRAZA_SUPERVISED" // the process was started by the dev supervisor
+	envBuildErr   = "BRAZA_BUILD_ERR"  // file with the last compile error
+)
+
+var (
+	defaultWatcherInclude = []string{"*.go", "go.mod", "go.sum", ".env", "*.env", "*.json", "*.yaml", "*.yml", "*.toml"}
+	defaultWatcherExclude = []string{".git", ".idea", ".vscode", "node_modules", "vendor", "*_test.go", "*.swp", "*~"}
 )
 
-func getFileStats() map[string]time.Time {
-	mapFiles := map[string]time.Time{}
-	rootpath, _ := os.Getwd()
-	filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
-		f, fErr := d.Info()
-		if fErr != nil {
+// if the app must start the dev supervisor instead of serving
+func needSupervisor(app *App) bool {
+	return app.Env == "development" && !app.DisableFileWatcher && os.Getenv(envSupervised) == ""
+}
+
+type devWatcher struct {
+	app     *App
+	root    string
+	include []string
+	exclude []string
+	fsw     *fsnotify.Watcher
+}
+
+func newDevWatcher(app *App) (*devWatcher, error) {
+	root, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &devWatcher{
+		app:     app,
+		root:    root,
+		include: app.WatcherInclude,
+		exclude: app.WatcherExclude,
+		fsw:     fsw,
+	}
+	if len(w.include) == 0 {
+		w.include = defaultWatcherInclude
+	}
+	if len(w.exclude) == 0 {
+		w.exclude = defaultWatcherExclude
+	}
+	return w, w.addDir(root)
+}
+
+// fsnotify is not recursive: watch all sub directories (the new ones are added on 'Create')
+func (w *devWatcher) addDir(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
 			return nil
 		}
-		if strings.HasSuffix(f.Name(), ".go") {
-			mapFiles[filepath.Join(rootpath, path)] = f.ModTime()
+		if path != w.root && w.match(w.exclude, path) {
+			return filepath.SkipDir
 		}
-		return nil
+		return w.fsw.Add(path)
 	})
-	return mapFiles
 }
 
-func fileWatcher(reboot chan bool) {
-	mapF := getFileStats()
-	for {
-		time.Sleep(time.Second)
-		for fn, lastMode := range mapF {
-			f, err := os.Open(fn)
-			if err != nil {
-				reboot <- true
-			}
-			fs, _ := f.Stat()
-			f.Close()
-			if fs.ModTime().After(lastMode) {
-				mapF[fn] = fs.ModTime()
-				reboot <- true
-			}
+// match the glob patterns with the file name and the path relative to the root
+func (w *devWatcher) match(patterns []string, path string) bool {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		rel = path
+	}
+	name := filepath.Base(path)
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(p, rel); ok {
+			return true
+		}
+		if strings.HasPrefix(rel, strings.TrimSuffix(p, "/")+string(filepath.Separator)) {
+			return true
 		}
 	}
+	return false
 }
 
-func selfReboot() {
-	fmt.Println()
-	l.warn.Print("Changes detected, reloading server...\n\n")
-	self, _ := os.Getwd()
-	for _, app := range mapStackApps {
-		app.Srv.Close()
+func (w *devWatcher) inFolder(folder, path string) bool {
+	if folder == "" {
+		return false
 	}
+	abs, err := filepath.Abs(folder)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(path, abs+string(filepath.Separator))
+}
 
-	time.Sleep(time.Duration(time.Second))
+/*
+returns what the change requires:
+  - rebuild: go source changed
+  - restart: config changed (or a template, if the template reloader is disabled)
 
-	nArgs := append([]string{"run", self}, os.Args[1:]...)
+the static files are always read from disk
+*/
+func (w *devWatcher) classify(path string) (rebuild, restart bool) {
+	if w.match(w.exclude, path) {
+		return false, false
+	}
+	switch {
+	case w.inFolder(w.app.StaticFolder, path):
+		return false, false
+	case w.inFolder(w.app.TemplateFolder, path):
+		return false, w.app.DisableTemplateReloader
+	case strings.HasSuffix(path, ".go"), filepath.Base(path) == "go.mod", filepath.Base(path) == "go.sum":
+		return w.match(w.include, path), false
+	}
+	return false, w.match(w.include, path)
+}
 
-	errBuf := bytes.NewBufferString("")
-	cmd := exec.Command("go", nArgs...)
+/*
+runs the app as a child process and restarts it when the source changes:
+  - debounce the events of file system
+  - build before stop the running child: if the build fails, the last successful build is restarted and shows the error in the browser
+*/
+func runSupervisor(app *App) error {
+	w, err := newDevWatcher(app)
+	if err != nil {
+		return err
+	}
+	defer w.fsw.Close()
 
-	cmd.Stdout = os.Stdout
+	tmpDir, err := os.MkdirTemp("", "braza-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	errFile := filepath.Join(tmpDir, "build.err")
+
+	bin, err := os.Executable() // the first run is the current binary
+	if err != nil {
+		return err
+	}
+	child := startChild(bin, errFile)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+	var (
+		timer   = time.NewTimer(0)
+		rebuild bool
+		builds  int
+	)
+	<-timer.C
+
+	for {
+		select {
+		case <-stop:
+			stopChild(child)
+			return nil
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return nil
+			}
+			l.err.Println("watcher:", err)
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return nil
+			}
+			if ev.Has(fsnotify.Create) {
+				if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() && !w.match(w.exclude, ev.Name) {
+					w.addDir(ev.Name)
+					continue
+				}
+			}
+			if ev.Has(fsnotify.Chmod) {
+				continue
+			}
+			b, r := w.classify(ev.Name)
+			if !b && !r {
+				continue
+			}
+			rebuild = rebuild || b
+			timer.Reset(app.WatcherDebounce)
+		case <-timer.C:
+			fmt.Println()
+			if rebuild {
+				l.warn.Print("Changes detected, rebuilding...")
+				builds++
+				newBin := filepath.Join(tmpDir, fmt.Sprintf("app-%d", builds))
+				if out, err := buildBinary(newBin); err != nil {
+					l.err.Printf("build failed, restarting the last successful build:\n%s", out)
+					os.WriteFile(errFile, out, 0644)
+					stopChild(child)
+					child = startChild(bin, errFile) // reads the error on start
+					rebuild = false
+					continue
+				}
+				os.Remove(errFile)
+				stopChild(child)
+				if strings.HasPrefix(bin, tmpDir) {
+					os.Remove(bin)
+				}
+				bin = newBin
+			} else {
+				l.warn.Print("Changes detected, reloading server...")
+				stopChild(child)
+			}
+			child = startChild(bin, errFile)
+			rebuild = false
+		}
+	}
+}
+
+func buildBinary(out string) ([]byte, error) {
+	errBuf := bytes.NewBufferString("")
+	cmd := exec.Command("go", "build", "-o", out, ".")
+	cmd.Stdout = errBuf
 	cmd.Stderr = errBuf
-	cmd.Stdin = os.Stdin
 	cmd.Env = os.Environ()
 	err := cmd.Run()
+	return errBuf.Bytes(), err
+}
+
+func startChild(bin, errFile string) *exec.Cmd {
+	cmd := exec.Command(bin, os.Args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+	cmd.Env = append(os.Environ(), envSupervised+"=1", envBuildErr+"="+errFile)
+	if err := cmd.Start(); err != nil {
+		l.err.Println(err)
+		return nil
+	}
+	go cmd.Wait() // reap the child if it dies on its own
+	return cmd
+}
 
-	if err != nil && err.Error() == "exit status 1" {
-		l.err.Println(errBuf.String())
+// stop the child with a interrupt, killing it if it does not exit in 5 seconds
+func stopChild(cmd *exec.Cmd) {
+	if cmd == nil || cmd.Process == nil {
+		return
 	}
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		cmd.Process.Kill()
+		return
+	}
+	for i := 0; i < 50; i++ {
+		if cmd.Process.Signal(syscall.Signal(0)) != nil {
+			return
+		}
+		time.Sleep(time.Second / 10)
+	}
+	cmd.Process.Kill()
+}
+
+// in a supervised process, returns the last compile error (if the build failed).
+// the file is read once: after a failed build, the supervisor restarts the process
+var devBuildError = sync.OnceValue(func() string {
+	errFile := os.Getenv(envBuildErr)
+	if errFile == "" {
+		return ""
+	}
+	b, err := os.ReadFile(errFile)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+})
+
+// shows the compile error in the browser, while the last successful build is running
+func devBuildErrorHandler(ctx *Ctx, buildErr string) {
+	ctx.HTML(
+		"<!DOCTYPE html><html><head><title>Build failed</title></head><body>"+
+			"<h2>Build failed</h2><p>the server is running the last successful build</p>"+
+			"<pre>"+c3po.HtmlEscape(buildErr)+"</pre></body></html>",
+		500,
+	)
 }
@@ -0,0 +1,57 @@
+package braza
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDevBuildErrorPage(t *testing.T) {
+	old := devBuildError
+	t.Cleanup(func() { devBuildError = old })
+	devBuildError = func() string { return "main.go:1: undefined: <foo>" }
+
+	app := newTestApp(t, nil, GET("/", func(ctx *Ctx) { ctx.TEXT("ok", 200) }))
+	rec := doRequest(app, "GET", "/", "")
+	expectStatus(t, rec, 500)
+	if !strings.Contains(rec.Body.String(), "undefined: &lt;foo&gt;") {
+		t.Errorf("the build error was not shown (escaped): %s", rec.Body.String())
+	}
+
+	app.Env = "production"
+	expectStatus(t, doRequest(app, "GET", "/", ""), 200)
+}
+
+func TestWatcherClassify(t *testing.T) {
+	root := t.TempDir()
+	app := &App{Config: &Config{StaticFolder: root + "/assets", TemplateFolder: root + "/templates"}}
+	w := &devWatcher{app: app, root: root, include: defaultWatcherInclude, exclude: defaultWatcherExclude}
+
+	cases := []struct {
+		path             string
+		rebuild, restart bool
+	}{
+		{"main.go", true, false},
+		{"pkg/db/db.go", true, false},
+		{"go.mod", true, false},
+		{"main_test.go", false, false},
+		{"vendor/x/x.go", false, false},
+		{".git/HEAD", false, false},
+		{"config.yaml", false, true},
+		{".env", false, true},
+		{"README.md", false, false},
+		{"assets/app.js", false, false},
+		{"templates/index.html", false, false},
+	}
+	for _, c := range cases {
+		rebuild, restart := w.classify(filepath.Join(root, c.path))
+		if rebuild != c.rebuild || restart != c.restart {
+			t.Errorf("%s: got rebuild %v restart %v, want %v %v", c.path, rebuild, restart, c.rebuild, c.restart)
+		}
+	}
+
+	app.DisableTemplateReloader = true
+	if _, restart := w.classify(filepath.Join(root, "templates/index.html")); !restart {
+		t.Error("without the template reloader, a template change must restart the server")
+	}
+}
@@ -0,0 +1,494 @@
+package braza
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+var (
+	ErrWsClosed    = errors.New("websocket: the connection is closed")
+	ErrWsQueueFull = errors.New("websocket: the send queue is full (slow client), the connection was closed")
+)
+
+/*
+A websocket endpoint: upgrades the request, registers the connection in the Hub and
+calls the callbacks. The writes are made by a goroutine per connection (with a send queue),
+and the ping/pong keepalive closes the dead connections. The hubs are closed by the app on
+shutdown and graceful reload (the connections are hijacked: Srv.Shutdown doesn't wait for them)
+
+	hub := braza.NewHub()
+
+	app.AddRoute(braza.WS("/chat/{room}", &braza.WsRoute{
+		Hub: hub,
+		OnConnect: func(c *braza.WsConn) error {
+			if c.Ctx.Session.Get("user") == "" {
+				return errors.New("unauthorized") // closes the connection
+			}
+			c.Join(c.Ctx.Request.PathArgs["room"])
+			return nil
+		},
+		OnMessage: func(c *braza.WsConn, msg *braza.WsMessage) {
+			var m ChatMessage
+			if err := msg.JSON(&m); err != nil {
+				c.SendJSON(map[string]string{"error": "invalid message"})
+				return
+			}
+			hub.BroadcastRoomJSON(c.Ctx.Request.PathArgs["room"], m)
+		},
+		OnClose: func(c *braza.WsConn, err error) {
+			log.Println(c.ID, "disconnected:", err)
+		},
+	}))
+*/
+type WsRoute struct {
+	Hub *Hub // the registry of connections (default a new hub per route)
+
+	OnConnect func(c *WsConn) error           // after the upgrade. a error closes the connection
+	OnMessage func(c *WsConn, msg *WsMessage) // each message received (text or binary)
+	OnClose   func(c *WsConn, err error)      // after the connection is closed (err is the reason)
+
+	Upgrader       *websocket.Upgrader // default is Router.WsUpgrader, or the upgrader of app
+	PingInterval   time.Duration       // interval of pings (default 9/10 of PongTimeout)
+	PongTimeout    time.Duration       // max time without a pong or message from client (default 60 seconds)
+	WriteTimeout   time.Duration       // max time of a write (default 10 seconds)
+	SendQueue      int                 // max messages waiting to be sent, per connection. if full, the connection is closed (default 256)
+	MaxMessageSize int64               // max size of received messages (default 1 MB)
+}
+
+/*
+Returns a GET route of websocket, mounted like any route (middlewares, routers, UrlFor...).
+The route is named by the callback (OnMessage, OnConnect or OnClose), like GET and friends
+
+	api := braza.NewRouter("api")
+	api.AddRoute(braza.WS("/events", &braza.WsRoute{OnMessage: onEvent}))
+*/
+func WS(url string, ws *WsRoute) *Route {
+	if ws == nil {
+		panic("websocket route is nil")
+	}
+	if ws.Hub == nil {
+		ws.Hub = NewHub()
+	}
+	if ws.PongTimeout <= 0 {
+		ws.PongTimeout = time.Second * 60
+	}
+	if ws.PingInterval <= 0 || ws.PingInterval >= ws.PongTimeout {
+		ws.PingInterval = ws.PongTimeout * 9 / 10
+	}
+	if ws.WriteTimeout <= 0 {
+		ws.WriteTimeout = time.Second * 10
+	}
+	if ws.SendQueue <= 0 {
+		ws.SendQueue = 256
+	}
+	if ws.MaxMessageSize <= 0 {
+		ws.MaxMessageSize = 1 << 20
+	}
+	r := &Route{
+		Url:     url,
+		Func:    ws.serve,
+		Methods: []string{"GET"},
+		hub:     ws.Hub,
+	}
+	switch {
+	case ws.OnMessage != nil:
+		r.Name = getFunctionName(ws.OnMessage)
+	case ws.OnConnect != nil:
+		r.Name = getFunctionName(ws.OnConnect)
+	case ws.OnClose != nil:
+		r.Name = getFunctionName(ws.OnClose)
+	}
+	return r
+}
+
+// a message received
+type WsMessage struct {
+	Type int // websocket.TextMessage or websocket.BinaryMessage
+	Data []byte
+}
+
+func (m *WsMessage) Text() string     { return string(m.Data) }
+func (m *WsMessage) JSON(v any) error { return json.Unmarshal(m.Data, v) }
+
+// a websocket connection. The methods are safe for concurrent use
+type WsConn struct {
+	ID  string
+	Ctx *Ctx // the request of upgrade (PathArgs, Session, Tenant, Global...)
+
+	conn  *websocket.Conn
+	route *WsRoute
+	hub   *Hub
+	send  chan *WsMessage
+
+	mu     sync.Mutex
+	closed bool
+	err    error // the reason of close
+	rooms  map[string]bool
+}
+
+// the raw connection of gorilla/websocket. Don't write in it (use Send), the writes are not concurrent
+func (c *WsConn) Conn() *websocket.Conn { return c.conn }
+func (c *WsConn) Hub() *Hub             { return c.hub }
+
+// Send a text message
+func (c *WsConn) Send(data []byte) error {
+	return c.enqueue(&WsMessage{Type: websocket.TextMessage, Data: data})
+}
+
+// Send a binary message
+func (c *WsConn) SendBinary(data []byte) error {
+	return c.enqueue(&WsMessage{Type: websocket.BinaryMessage, Data: data})
+}
+
+// Send the value encoded in json (text message)
+func (c *WsConn) SendJSON(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Send(b)
+}
+
+// put the message in the send queue, without block. a slow client (full queue) is disconnected
+func (c *WsConn) enqueue(msg *WsMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrWsClosed
+	}
+	select {
+	case c.send <- msg:
+		return nil
+	default:
+		c.hub.dropped.Add(1)
+		c.closeLocked(ErrWsQueueFull)
+		return ErrWsQueueFull
+	}
+}
+
+// Close the connection (the messages in queue are sent before)
+func (c *WsConn) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closeLocked(nil)
+}
+
+func (c *WsConn) closeLocked(err error) {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.err = err
+	close(c.send) // the writer sends a close message and closes the connection
+}
+
+// Join a room of hub
+func (c *WsConn) Join(room string) { c.hub.join(c, room) }
+
+// Leave a room of hub
+func (c *WsConn) Leave(room string) { c.hub.leave(c, room) }
+
+// Returns the rooms of connection
+func (c *WsConn) Rooms() []string {
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+	rooms := make([]string, 0, len(c.rooms))
+	for r := range c.rooms {
+		rooms = append(rooms, r)
+	}
+	return rooms
+}
+
+func (ws *WsRoute) serve(ctx *Ctx) {
+	if !ctx.Request.isWebsocket {
+		ctx.BadRequest()
+	}
+	var (
+		conn *websocket.Conn
+		err  error
+	)
+	if ws.Upgrader != nil {
+		conn, err = ws.Upgrader.Upgrade(ctx, ctx.Request.raw, ctx.Header())
+	} else {
+		conn, err = ctx.Request.Websocket()
+	}
+	if err != nil {
+		l.err.Println("websocket:", err)
+		ctx.Close() // the upgrader wrote the http error
+	}
+
+	c := &WsConn{
+		ID:    uuid.NewString(),
+		Ctx:   ctx,
+		conn:  conn,
+		route: ws,
+		hub:   ws.Hub,
+		send:  make(chan *WsMessage, ws.SendQueue),
+		rooms: map[string]bool{},
+	}
+	ws.Hub.add(c)
+	done := make(chan struct{})
+	go func() {
+		c.writePump()
+		close(done)
+	}()
+
+	err = c.connect()
+	if err == nil {
+		err = c.readPump()
+	}
+	c.mu.Lock()
+	c.closeLocked(err)
+	err = c.err
+	c.mu.Unlock()
+	<-done
+	ws.Hub.remove(c)
+	if ws.OnClose != nil {
+		ws.OnClose(c, err)
+	}
+}
+
+// exec the OnConnect, recovering the panics
+func (c *WsConn) connect() (err error) {
+	if c.route.OnConnect == nil {
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			l.err.Println("websocket:", r)
+			err = ErrWsClosed
+		}
+	}()
+	return c.route.OnConnect(c)
+}
+
+func (c *WsConn) onMessage(msg *WsMessage) {
+	defer func() {
+		if r := recover(); r != nil {
+			l.err.Println("websocket:", r)
+			c.Close()
+		}
+	}()
+	c.route.OnMessage(c, msg)
+}
+
+func (c *WsConn) readPump() error {
+	ws := c.route
+	c.conn.SetReadLimit(ws.MaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(ws.PongTimeout))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(ws.PongTimeout))
+	})
+	for {
+		mt, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				return nil
+			}
+			return err
+		}
+		c.conn.SetReadDeadline(time.Now().Add(ws.PongTimeout))
+		c.hub.received.Add(1)
+		if ws.OnMessage != nil {
+			c.onMessage(&WsMessage{Type: mt, Data: data})
+		}
+	}
+}
+
+func (c *WsConn) writePump() {
+	ws := c.route
+	ticker := time.NewTicker(ws.PingInterval)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close() // stops the readPump
+	}()
+	for {
+		select {
+		case msg, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(ws.WriteTimeout))
+			if !ok {
+				code, text := websocket.CloseNormalClosure, ""
+				if c.err != nil {
+					code, text = websocket.ClosePolicyViolation, c.err.Error()
+				}
+				c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, text))
+				return
+			}
+			if err := c.conn.WriteMessage(msg.Type, msg.Data); err != nil {
+				return
+			}
+			c.hub.sent.Add(1)
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(ws.WriteTimeout))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+/*
+Registry of websocket connections, with rooms. Can be shared by many WsRoutes
+
+	hub := braza.NewHub()
+	hub.Broadcast([]byte("hello everyone"))
+	hub.BroadcastRoomJSON("room1", msg, sender) // except the sender
+	hub.Metrics() // {Connections: 10, Rooms: 2, ...}
+*/
+type Hub struct {
+	mu    sync.RWMutex
+	conns map[*WsConn]bool
+	rooms map[string]map[*WsConn]bool
+
+	received atomic.Uint64
+	sent     atomic.Uint64
+	dropped  atomic.Uint64
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		conns: map[*WsConn]bool{},
+		rooms: map[string]map[*WsConn]bool{},
+	}
+}
+
+// metrics of hub
+type HubMetrics struct {
+	Connections      int    `json:"connections"`
+	Rooms            int    `json:"rooms"`
+	MessagesReceived uint64 `json:"messagesReceived"`
+	MessagesSent     uint64 `json:"messagesSent"`
+	SlowClients      uint64 `json:"slowClients"` // connections closed by full send queue
+}
+
+func (h *Hub) Metrics() HubMetrics {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return HubMetrics{
+		Connections:      len(h.conns),
+		Rooms:            len(h.rooms),
+		MessagesReceived: h.received.Load(),
+		MessagesSent:     h.sent.Load(),
+		SlowClients:      h.dropped.Load(),
+	}
+}
+
+func (h *Hub) add(c *WsConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.conns[c] = true
+}
+
+func (h *Hub) remove(c *WsConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.conns, c)
+	for room := range c.rooms {
+		h.leaveLocked(c, room)
+	}
+}
+
+func (h *Hub) join(c *WsConn, room string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.conns[c] {
+		return // closed
+	}
+	if h.rooms[room] == nil {
+		h.rooms[room] = map[*WsConn]bool{}
+	}
+	h.rooms[room][c] = true
+	c.rooms[room] = true
+}
+
+func (h *Hub) leave(c *WsConn, room string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.leaveLocked(c, room)
+}
+
+func (h *Hub) leaveLocked(c *WsConn, room string) {
+	delete(c.rooms, room)
+	if conns, ok := h.rooms[room]; ok {
+		delete(conns, c)
+		if len(conns) == 0 {
+			delete(h.rooms, room)
+		}
+	}
+}
+
+// Returns the connections of hub
+func (h *Hub) Conns() []*WsConn {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.snapshot(h.conns)
+}
+
+// Returns the connections of room
+func (h *Hub) Room(room string) []*WsConn {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.snapshot(h.rooms[room])
+}
+
+func (h *Hub) snapshot(conns map[*WsConn]bool) []*WsConn {
+	list := make([]*WsConn, 0, len(conns))
+	for c := range conns {
+		list = append(list, c)
+	}
+	return list
+}
+
+func send(conns []*WsConn, msg []byte, except []*WsConn) {
+	for _, c := range conns {
+		skip := false
+		for _, e := range except {
+			if c == e {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			c.Send(msg) // never blocks: the slow clients are disconnected
+		}
+	}
+}
+
+// Send a text message to all connections (except the 'except')
+func (h *Hub) Broadcast(msg []byte, except ...*WsConn) { send(h.Conns(), msg, except) }
+
+// Send a text message to the connections of room (except the 'except')
+func (h *Hub) BroadcastRoom(room string, msg []byte, except ...*WsConn) {
+	send(h.Room(room), msg, except)
+}
+
+// Send the value encoded in json to all connections (except the 'except')
+func (h *Hub) BroadcastJSON(v any, except ...*WsConn) error {
+	b, err := json.Marshal(v)
+	if err == nil {
+		h.Broadcast(b, except...)
+	}
+	return err
+}
+
+// Send the value encoded in json to the connections of room (except the 'except')
+func (h *Hub) BroadcastRoomJSON(room string, v any, except ...*WsConn) error {
+	b, err := json.Marshal(v)
+	if err == nil {
+		h.BroadcastRoom(room, b, except...)
+	}
+	return err
+}
+
+// Close all connections
+func (h *Hub) Close() {
+	for _, c := range h.Conns() {
+		c.Close()
+	}
+}
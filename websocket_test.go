@@ -0,0 +1,127 @@
+package braza
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func wsEcho(c *WsConn, msg *WsMessage) { c.Send(msg.Data) }
+
+func wsGreet(c *WsConn) error { return c.Send([]byte("hello")) }
+
+func TestWsRouteNames(t *testing.T) {
+	app := newTestApp(t, nil,
+		WS("/echo", &WsRoute{OnMessage: wsEcho}),
+		WS("/greet", &WsRoute{OnConnect: wsGreet}),
+	)
+	for _, name := range []string{"wsEcho", "wsGreet"} {
+		if _, ok := app.routesByName[name]; !ok {
+			t.Errorf("the route '%s' was not registered", name)
+		}
+	}
+}
+
+func TestWsEcho(t *testing.T) {
+	app := newTestApp(t, nil, WS("/echo", &WsRoute{OnConnect: wsGreet, OnMessage: wsEcho}))
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/echo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+
+	if _, msg, err := conn.ReadMessage(); err != nil || string(msg) != "hello" {
+		t.Fatalf("OnConnect: got %q, %v", msg, err)
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	if _, msg, err := conn.ReadMessage(); err != nil || string(msg) != "ping" {
+		t.Fatalf("OnMessage: got %q, %v", msg, err)
+	}
+}
+
+func TestWsRooms(t *testing.T) {
+	hub := NewHub()
+	closed := make(chan error, 4)
+	app := newTestApp(t, nil, WS("/chat/{room}", &WsRoute{
+		Hub: hub,
+		OnConnect: func(c *WsConn) error {
+			if c.Ctx.Request.PathArgs["room"] == "private" {
+				return errors.New("forbidden")
+			}
+			c.Join(c.Ctx.Request.PathArgs["room"])
+			return c.Send([]byte("joined"))
+		},
+		OnMessage: func(c *WsConn, msg *WsMessage) {
+			hub.BroadcastRoom(c.Ctx.Request.PathArgs["room"], msg.Data, c)
+		},
+		OnClose: func(c *WsConn, err error) { closed <- err },
+	}))
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	dial := func(room string) *websocket.Conn {
+		t.Helper()
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/chat/"+room, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+		if _, msg, err := conn.ReadMessage(); err != nil || string(msg) != "joined" {
+			t.Fatalf("join: %q, %v", msg, err)
+		}
+		return conn
+	}
+	a, b, other := dial("x"), dial("x"), dial("y")
+
+	a.WriteMessage(websocket.TextMessage, []byte("hi"))
+	if _, msg, err := b.ReadMessage(); err != nil || string(msg) != "hi" {
+		t.Fatalf("broadcast in room: %q, %v", msg, err)
+	}
+	for name, conn := range map[string]*websocket.Conn{"sender": a, "other room": other} {
+		conn.SetReadDeadline(time.Now().Add(time.Millisecond * 100))
+		if _, msg, err := conn.ReadMessage(); err == nil {
+			t.Errorf("the %s received %q", name, msg)
+		}
+	}
+	if m := hub.Metrics(); m.Connections != 3 || m.Rooms != 2 {
+		t.Errorf("metrics: %+v", m)
+	}
+
+	// a error in OnConnect closes the connection
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/chat/private", nil)
+	if err == nil {
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+		if _, _, err := conn.ReadMessage(); err == nil {
+			t.Error("the connection refused by OnConnect is open")
+		}
+	}
+	select {
+	case err := <-closed:
+		if err == nil || err.Error() != "forbidden" {
+			t.Errorf("OnClose: the reason must be the error of OnConnect: %v", err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("OnClose was not called after the OnConnect error")
+	}
+
+	hub.Close()
+	for i := 0; i < 3; i++ {
+		select {
+		case <-closed:
+		case <-time.After(time.Second * 5):
+			t.Fatal("OnClose was not called after hub.Close")
+		}
+	}
+}